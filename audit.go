@@ -0,0 +1,229 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// ErrAuditSinkClosed The records can't be stored after closing the sink
+var ErrAuditSinkClosed = errors.New("audit sink is closed")
+
+// Outcomes of the audited mutations. AuditIntent is recorded before the
+// mutation runs, the others once it ended, with the same request id.
+const (
+	AuditIntent  = "intent"
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+	AuditPanic   = "panic"
+)
+
+// AuditRecord Who executed a mutation, with what and how it ended
+type AuditRecord struct {
+	Time      time.Time       `json:"time"`
+	RequestID string          `json:"request_id,omitempty"`
+	Subject   string          `json:"subject,omitempty"`
+	Operation string          `json:"operation"`
+	Fields    []string        `json:"fields"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+	Version   string          `json:"version,omitempty"`
+	Outcome   string          `json:"outcome"`
+	Errors    []string        `json:"errors,omitempty"`
+	// Unparsed The document couldn't be parsed here, though graphql-go may
+	// run it, so it is recorded without its operation, fields and variables
+	Unparsed bool `json:"unparsed,omitempty"`
+}
+
+// AuditSink Store the audit records, returning only once the record is safe
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// AuditFailurePolicy What happens to a mutation whose intent couldn't be
+// recorded. A failure to record the outcome, once the mutation ran, is only
+// logged, so the client doesn't retry it.
+type AuditFailurePolicy int
+
+const (
+	// AuditFailClosed Don't run the mutation, answering an AUDIT_FAILED error
+	AuditFailClosed AuditFailurePolicy = iota
+	// AuditFailOpen Run the mutation anyway, logging the failure
+	AuditFailOpen
+)
+
+type auditService struct {
+	Service
+	sink     AuditSink
+	policy   AuditFailurePolicy
+	redactor *redactor
+	logger   log.Logger
+}
+
+// NewAuditService Create a service recording every mutation in sink before
+// running it and once it ended, with the values of redaction and the
+// @sensitive ones omitted from its variables. Failures to record are logged
+// in logger.
+func NewAuditService(sink AuditSink, s Service, policy AuditFailurePolicy, redaction Redaction, logger log.Logger) Service {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &auditService{Service: s, sink: sink, policy: policy, redactor: newRedactor(redaction), logger: logger}
+}
+
+// AddAuditLog Record every mutation in sink, apart from the logs
+func (h *Handlers) AddAuditLog(sink AuditSink) {
+	h.auditSink = sink
+}
+
+// AddAuditFailurePolicy Choose what happens to a mutation whose intent
+// couldn't be recorded, AuditFailClosed by default
+func (h *Handlers) AddAuditFailurePolicy(policy AuditFailurePolicy) {
+	h.auditPolicy = policy
+}
+
+func (h *Handlers) addAuditLog() {
+	if h.auditSink == nil {
+		return
+	}
+	h.service = NewAuditService(h.auditSink, h.service, h.auditPolicy, h.logRedaction, h.logger)
+}
+
+func (s *auditService) Exec(ctx context.Context, req GraphqlRequest) (res *graphql.Response) {
+	var record AuditRecord
+	if doc, err := documentFor(ctx, req.Query); err != nil {
+		record = s.newUnparsedRecord(ctx)
+	} else {
+		op := doc.operation(req.OperationName)
+		if op == nil || op.kind != "mutation" {
+			return s.Service.Exec(ctx, req)
+		}
+		record = s.newRecord(ctx, req, doc, op)
+	}
+	record.Outcome = AuditIntent
+	if err := s.record(ctx, record); err != nil && s.policy == AuditFailClosed {
+		return &graphql.Response{Errors: []*gqlerrors.QueryError{{
+			Message:    "mutation could not be audited",
+			Extensions: map[string]interface{}{"code": "AUDIT_FAILED"},
+		}}}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			record.Time = time.Now().UTC()
+			record.Outcome = AuditPanic
+			record.Errors = []string{fmt.Sprint(r)}
+			s.record(ctx, record)
+			panic(r)
+		}
+	}()
+	res = s.Service.Exec(ctx, req)
+	record.Time = time.Now().UTC()
+	record.Outcome = AuditSuccess
+	if len(res.Errors) > 0 {
+		record.Outcome = AuditFailure
+		for _, e := range res.Errors {
+			record.Errors = append(record.Errors, e.Message)
+		}
+	}
+	s.record(ctx, record)
+	return res
+}
+
+func (s *auditService) newRecord(ctx context.Context, req GraphqlRequest, doc *document, op *operation) AuditRecord {
+	record := s.newUnparsedRecord(ctx)
+	record.Unparsed = false
+	record.Operation = op.name
+	for _, f := range doc.rootFields(op) {
+		record.Fields = append(record.Fields, f.name)
+	}
+	if record.Operation == "" && len(record.Fields) > 0 {
+		record.Operation = record.Fields[0]
+	}
+	if req.Variables != nil {
+		var sensitive sensitiveValues
+		if schema := astSchemaOf(ctx, s.Service); schema != nil {
+			sensitive = sensitivePaths(ctx, schema, req)
+		}
+		if variables, err := json.Marshal(req.Variables); err == nil {
+			record.Variables = sensitive.redactVariables(s.redactor, variables)
+		}
+	}
+	return record
+}
+
+// newUnparsedRecord Record a request whose document couldn't be parsed, as
+// it may be a mutation
+func (s *auditService) newUnparsedRecord(ctx context.Context) AuditRecord {
+	record := AuditRecord{Time: time.Now().UTC(), Fields: []string{}, Unparsed: true}
+	record.RequestID, _ = ctx.Value(httptransport.ContextKeyRequestXRequestID).(string)
+	record.Subject, _ = claimsSubject(ctx)
+	record.Version, _ = ctx.Value(VersionKey).(string)
+	return record
+}
+
+func (s *auditService) record(ctx context.Context, record AuditRecord) error {
+	err := s.sink.Record(ctx, record)
+	if err != nil {
+		level.Error(s.logger).Log(
+			"msg", "audit record failed",
+			"x-req-id", record.RequestID,
+			"operation", record.Operation,
+			"error", err,
+		)
+	}
+	return err
+}
+
+// FileAuditSink Append the audit records to a file, one json per line
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink Open, or create, the file at path to append the records,
+// each one synced to disk before Record returns
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+// Record Append record to the file
+func (s *FileAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrAuditSinkClosed
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close Close the file, failing the records afterwards
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
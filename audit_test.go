@@ -0,0 +1,211 @@
+package graphqlkit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	err     error
+}
+
+func (s *memoryAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func serveAudited(t *testing.T, sink AuditSink, body string, opts ...Option) *httptest.ResponseRecorder {
+	return serveAuditedWith(t, &sensitiveResolver{}, sink, body, opts...)
+}
+
+func serveAuditedWith(t *testing.T, resolver interface{}, sink AuditSink, body string, opts ...Option) *httptest.ResponseRecorder {
+	opts = append(opts,
+		WithSchemaSources(SchemaString("schema.graphql", sensitiveSchema)),
+		WithSchemaOptions(graphql.UseFieldResolvers()),
+		WithAuditLog(sink),
+	)
+	h, err := NewHandlers("", resolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "req-1")
+	resp := httptest.NewRecorder()
+	h.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+const auditedMutation = `{"query":"mutation Pay($in: PaymentInput!) { pay(input: $in) { name } }",` +
+	`"variables":{"in":{"amount":10,"card":{"holder":"john","number":"4111111111111111"}}}}`
+
+func TestHandlers_AddAuditLog_RecordsMutations(t *testing.T) {
+	//Arrange
+	sink := &memoryAuditSink{}
+
+	//Act
+	serveAudited(t, sink, auditedMutation)
+	serveAudited(t, sink, `{"query":"{ account { name } }"}`)
+
+	//Assert
+	if len(sink.records) != 2 {
+		t.Fatalf("Should have recorded only the mutation, before and after it ran, and returned %v\n", sink.records)
+	}
+	if intent := sink.records[0]; intent.Operation != "Pay" || intent.Outcome != AuditIntent {
+		t.Errorf("Should have recorded the intent first and returned %+v\n", intent)
+	}
+	record := sink.records[1]
+	if record.Operation != "Pay" || record.Fields[0] != "pay" || record.RequestID != "req-1" || record.Outcome != AuditSuccess {
+		t.Errorf("Should have recorded the mutation and returned %+v\n", record)
+	}
+	if want := `{"in":{"amount":10,"card":{"holder":"john","number":"(omitted)"}}}`; string(record.Variables) != want {
+		t.Errorf("Should have recorded the redacted variables %v and returned %s\n", want, record.Variables)
+	}
+}
+
+func TestHandlers_AddAuditLog_FailurePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy AuditFailurePolicy
+		want   string
+	}{
+		{"Fail closed", AuditFailClosed, `"code":"AUDIT_FAILED"`},
+		{"Fail open", AuditFailOpen, `"pay":{"name":"john"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memoryAuditSink{err: errors.New("disk full")}
+			resp := serveAudited(t, sink, auditedMutation, WithAuditFailurePolicy(tt.policy))
+			if !strings.Contains(resp.Body.String(), tt.want) {
+				t.Errorf("Should have answered %v and returned %v\n", tt.want, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_AddAuditLog_WithFailingSink_ShouldNotRunTheMutation(t *testing.T) {
+	//Arrange
+	sink := &memoryAuditSink{err: errors.New("disk full")}
+	resolver := &payCountingResolver{}
+
+	//Act
+	resp := serveAuditedWith(t, resolver, sink, auditedMutation)
+
+	//Assert
+	if resolver.pays != 0 || !strings.Contains(resp.Body.String(), `"code":"AUDIT_FAILED"`) {
+		t.Errorf("Should have refused the mutation it couldn't audit and returned %s, paid %d times\n", resp.Body.String(), resolver.pays)
+	}
+}
+
+func TestAuditService_WithOutcomeNotRecorded_ShouldAnswerTheResponse(t *testing.T) {
+	//Arrange
+	sink := &outcomeFailingSink{}
+	stub := &stubService{res: &graphql.Response{Data: json.RawMessage(`{"bump":1}`)}}
+	s := NewAuditService(sink, stub, AuditFailClosed, Redaction{}, nil)
+
+	//Act
+	res := s.Exec(context.Background(), GraphqlRequest{Query: "mutation { bump }"})
+
+	//Assert
+	if len(res.Errors) > 0 || string(res.Data) != `{"bump":1}` {
+		t.Errorf("Should have answered the mutation that already ran and returned %+v\n", res)
+	}
+}
+
+// outcomeFailingSink Record the intents only
+type outcomeFailingSink struct {
+	memoryAuditSink
+}
+
+func (s *outcomeFailingSink) Record(ctx context.Context, record AuditRecord) error {
+	if record.Outcome != AuditIntent {
+		return errors.New("disk full")
+	}
+	return s.memoryAuditSink.Record(ctx, record)
+}
+
+func TestFileAuditSink(t *testing.T) {
+	//Arrange
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Act
+	sink.Record(context.Background(), AuditRecord{Operation: "a", Outcome: AuditSuccess})
+	sink.Record(context.Background(), AuditRecord{Operation: "b", Outcome: AuditFailure})
+	sink.Close()
+	closedErr := sink.Record(context.Background(), AuditRecord{Operation: "c"})
+
+	//Assert
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var operations []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		operations = append(operations, record.Operation)
+	}
+	if strings.Join(operations, ",") != "a,b" {
+		t.Errorf("Should have appended a line for each record and returned %v\n", operations)
+	}
+	if closedErr != ErrAuditSinkClosed {
+		t.Errorf("Should have failed after closing and returned %v\n", closedErr)
+	}
+}
+
+func TestAuditService_WithUnparsableDocument_ShouldRecordIt(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Parsed", "mutation { bump }"},
+		{"Unparsable", "mutation { bump } /* x */"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Arrange
+			sink := &memoryAuditSink{}
+			stub := &stubService{res: &graphql.Response{Data: json.RawMessage(`{"bump":1}`)}}
+			s := NewAuditService(sink, stub, AuditFailClosed, Redaction{}, nil)
+
+			//Act
+			s.Exec(context.Background(), GraphqlRequest{Query: tt.query, Variables: map[string]interface{}{"a": 1}})
+
+			//Assert
+			if len(sink.records) != 2 {
+				t.Fatalf("Should have recorded the mutation and returned %v\n", sink.records)
+			}
+			record := sink.records[1]
+			unparsed := tt.name == "Unparsable"
+			if record.Unparsed != unparsed || record.Outcome != AuditSuccess || (unparsed && record.Variables != nil) {
+				t.Errorf("Should have recorded it with unparsed %v and returned %+v\n", unparsed, record)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package graphqlkit
 
 import (
 	"context"
+	"reflect"
 
 	kitjwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/endpoint"
@@ -16,17 +17,7 @@ func MakeAuthenticationEndPoint(
 ) endpoint.Endpoint {
 	auth := JwtEndpoint{
 		keyFunc: func(token *jwt.Token) (interface{}, error) {
-			switch method.Alg() {
-			case "EdDSA":
-				return jwt.ParseEdPublicKeyFromPEM(key)
-			case "ES256", "ES384", "ES512":
-				return jwt.ParseECPublicKeyFromPEM(key)
-			case "RS256", "RS384", "RS512":
-				return jwt.ParseRSAPublicKeyFromPEM(key)
-			case "HS256", "HS384", "HS512":
-				return key, nil
-			}
-			return key, nil
+			return parseVerificationKey(key, method)
 		},
 		method:    method,
 		newClaims: newClaims,
@@ -34,6 +25,41 @@ func MakeAuthenticationEndPoint(
 	return auth.NewParser(end)
 }
 
+// parseVerificationKey Convert the configured key to the type expected by
+// the signing method, parsing PEM encoded public keys when needed
+func parseVerificationKey(key []byte, method jwt.SigningMethod) (interface{}, error) {
+	switch method.Alg() {
+	case "EdDSA":
+		return jwt.ParseEdPublicKeyFromPEM(key)
+	case "ES256", "ES384", "ES512":
+		return jwt.ParseECPublicKeyFromPEM(key)
+	case "RS256", "RS384", "RS512":
+		return jwt.ParseRSAPublicKeyFromPEM(key)
+	case "HS256", "HS384", "HS512":
+		return key, nil
+	}
+	return key, nil
+}
+
+// claimsSubject Return the subject of the jwt claims of ctx, read from the
+// "sub" key of jwt.MapClaims or the Subject field of a claims struct
+func claimsSubject(ctx context.Context) (string, bool) {
+	claims := ctx.Value(kitjwt.JWTClaimsContextKey)
+	if mapClaims, ok := claims.(jwt.MapClaims); ok {
+		subject, ok := mapClaims["sub"].(string)
+		return subject, ok
+	}
+	claimsValue := reflect.Indirect(reflect.ValueOf(claims))
+	if claimsValue.Kind() != reflect.Struct {
+		return "", false
+	}
+	subjectValue := claimsValue.FieldByName("Subject")
+	if !subjectValue.IsValid() || subjectValue.Kind() != reflect.String {
+		return "", false
+	}
+	return subjectValue.String(), true
+}
+
 // JwtEndpoint Struct with all parameters for NewParser from jwt
 type JwtEndpoint struct {
 	keyFunc   jwt.Keyfunc
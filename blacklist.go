@@ -16,10 +16,7 @@ func makeBlacklistMiddleware(end endpoint.Endpoint, blacklist []string) endpoint
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 			req := request.(GraphqlRequest)
-			if req.OperationName == "" {
-				req.OperationName = findOpName(req.Query)
-			}
-			if bl[strings.ToUpper(req.OperationName)] {
+			if bl[strings.ToUpper(operationMethod(ctx, req))] {
 				return next(ctx, request)
 			}
 			return end(ctx, request)
@@ -27,8 +24,25 @@ func makeBlacklistMiddleware(end endpoint.Endpoint, blacklist []string) endpoint
 	}
 }
 
+// operationMethod Return the operation name of req or, when it has none, the
+// name of the first field at its root, never its alias, using the document
+// cache of ctx if there is one
+func operationMethod(ctx context.Context, req GraphqlRequest) string {
+	if req.OperationName != "" {
+		return req.OperationName
+	}
+	if doc, err := documentFor(ctx, req.Query); err == nil {
+		if op := doc.operation(""); op != nil {
+			if fields := doc.rootFields(op); len(fields) > 0 {
+				return fields[0].name
+			}
+		}
+	}
+	return findOpName(req.Query)
+}
+
 func findOpName(req string) string {
-	findOpAfterBracesWithOrWithoutSpace := "{([\t\n\v\f\r ]*)([0-9A-Za-z_]+)"
+	findOpAfterBracesWithOrWithoutSpace := "{[\t\n\v\f\r ]*(?:[0-9A-Za-z_]+[\t\n\v\f\r ]*:[\t\n\v\f\r ]*)?([0-9A-Za-z_]+)"
 	r := regexp.MustCompile(findOpAfterBracesWithOrWithoutSpace)
 	sm := r.FindStringSubmatch(req)
 	str := ""
@@ -16,6 +16,11 @@ func Test_findOpName(t *testing.T) {
 			args{"{  qn }"},
 			"qn",
 		},
+		{
+			"Alias",
+			args{"{ login : secretField }"},
+			"secretField",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,338 @@
+package graphqlkit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+const cacheControlKey contextKey = "cacheControl"
+
+// ResponseCache Store the responses of cacheable queries, see NewLRUCache
+type ResponseCache interface {
+	// Get Return the value stored with key, if it didn't expire
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set Store value with key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// cachePolicy How long and by whom a response may be cached, the minimum of
+// the @cacheControl hints of the fields of the query
+type cachePolicy struct {
+	maxAge  int
+	private bool
+}
+
+type cacheService struct {
+	Service
+	cache     ResponseCache
+	bySubject bool
+}
+
+// NewCacheService Create a service caching the responses of queries whose
+// fields all have a @cacheControl(maxAge, scope) hint, declared in the schema
+// of s as
+//
+//	enum CacheControlScope { PUBLIC PRIVATE }
+//	directive @cacheControl(maxAge: Int, scope: CacheControlScope) on FIELD_DEFINITION | OBJECT | INTERFACE | UNION
+//
+// A field without a hint takes it from its type, fields returning scalars
+// may go without one. The response lives for the minimum maxAge of its
+// fields, PRIVATE responses are cached by the jwt subject as well as all of
+// them when bySubject is true. Responses with errors are never cached.
+func NewCacheService(cache ResponseCache, s Service, bySubject bool) Service {
+	return &cacheService{Service: s, cache: cache, bySubject: bySubject}
+}
+
+// AddResponseCache Cache the responses of queries with @cacheControl hints in
+// cache, see NewCacheService. The Cache-Control header of those responses
+// tells clients and proxies how long they may be cached.
+func (h *Handlers) AddResponseCache(cache ResponseCache, bySubject bool) {
+	h.responseCache = cache
+	h.cacheBySubject = bySubject
+}
+
+func (h *Handlers) addResponseCache() {
+	if h.responseCache == nil {
+		return
+	}
+	h.service = NewCacheService(h.responseCache, h.service, h.cacheBySubject)
+	h.AddServerOptions(
+		httptransport.ServerBefore(cacheControlToCtx()),
+		httptransport.ServerAfter(cacheControlHeader()),
+	)
+}
+
+func (s *cacheService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	schema := astSchemaOf(ctx, s.Service)
+	if schema == nil {
+		return s.Service.Exec(ctx, req)
+	}
+	policy, ok := queryCachePolicy(ctx, schema, req)
+	if !ok {
+		return s.Service.Exec(ctx, req)
+	}
+	subject, authenticated := claimsSubject(ctx)
+	if policy.private && !authenticated {
+		return s.Service.Exec(ctx, req)
+	}
+	if !policy.private && !s.bySubject {
+		subject = ""
+	}
+	key, err := cacheKey(ctx, req, subject)
+	if err != nil {
+		return s.Service.Exec(ctx, req)
+	}
+	if cached, ok := s.cache.Get(ctx, key); ok {
+		var res graphql.Response
+		if err := json.Unmarshal(cached, &res); err == nil {
+			setCacheControl(ctx, policy)
+			return &res
+		}
+	}
+	res := s.Service.Exec(ctx, req)
+	if len(res.Errors) > 0 {
+		return res
+	}
+	if encoded, err := json.Marshal(res); err == nil {
+		s.cache.Set(ctx, key, encoded, time.Duration(policy.maxAge)*time.Second)
+		setCacheControl(ctx, policy)
+	}
+	return res
+}
+
+// cacheKey Hash the normalized query, operation, variables, version and
+// subject of the request
+func cacheKey(ctx context.Context, req GraphqlRequest, subject string) (string, error) {
+	query, err := normalizeQuery(req.Query)
+	if err != nil {
+		return "", err
+	}
+	variables, err := json.Marshal(req.Variables)
+	if err != nil {
+		return "", err
+	}
+	version, _ := ctx.Value(VersionKey).(string)
+	hash := sha256.New()
+	for _, part := range []string{query, req.OperationName, string(variables), version, subject} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// astSchemaOf Return the schema s executes the request of ctx with,
+// following reloads, versions and decorators, or nil if s isn't a graphql
+// service
+func astSchemaOf(ctx context.Context, s Service) *types.Schema {
+	if service := graphqlServiceOf(ctx, s); service != nil {
+		return service.schema().ASTSchema()
+	}
+	return nil
+}
+
+// graphqlServiceOf Return the graphql service executing the request of ctx
+// in s, following versions and decorators, or nil if there is none
+func graphqlServiceOf(ctx context.Context, s Service) *graphqlService {
+	switch s := s.(type) {
+	case *graphqlService:
+		return s
+	case *versionedService:
+		version, _ := ctx.Value(VersionKey).(string)
+		return graphqlServiceOf(ctx, s.services[version])
+	case *cacheService:
+		return graphqlServiceOf(ctx, s.Service)
+	case *introspectionService:
+		return graphqlServiceOf(ctx, s.Service)
+	case *auditService:
+		return graphqlServiceOf(ctx, s.Service)
+	case *loggingService:
+		return graphqlServiceOf(ctx, s.Service)
+	case *captureService:
+		return graphqlServiceOf(ctx, s.Service)
+	}
+	return nil
+}
+
+// queryCachePolicy Compute the cache policy of the operation of req,
+// reporting false if it can't be cached
+func queryCachePolicy(ctx context.Context, schema *types.Schema, req GraphqlRequest) (cachePolicy, bool) {
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return cachePolicy{}, false
+	}
+	op := doc.operation(req.OperationName)
+	if op == nil || op.kind != "query" {
+		return cachePolicy{}, false
+	}
+	w := &cachePolicyWalker{schema: schema, doc: doc, policy: cachePolicy{maxAge: -1}, visited: map[string]bool{}}
+	w.walk(op.selections, schema.EntryPoints["query"], true)
+	if w.policy.maxAge <= 0 {
+		return cachePolicy{}, false
+	}
+	return w.policy, true
+}
+
+type cachePolicyWalker struct {
+	schema  *types.Schema
+	doc     *document
+	policy  cachePolicy
+	visited map[string]bool
+}
+
+func (w *cachePolicyWalker) restrict(maxAge int) {
+	if w.policy.maxAge < 0 || maxAge < w.policy.maxAge {
+		w.policy.maxAge = maxAge
+	}
+}
+
+// walk Restrict the policy by the fields of selections on parent
+func (w *cachePolicyWalker) walk(selections []selection, parent types.NamedType, root bool) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			w.walkField(s, parent, root)
+		case *inlineFragment:
+			typ := parent
+			if s.typeCondition != "" {
+				typ = w.schema.Types[s.typeCondition]
+			}
+			w.walk(s.selections, typ, root)
+		case *fragmentSpread:
+			frag, ok := w.doc.fragments[s.name]
+			if !ok || w.visited[s.name] {
+				continue
+			}
+			w.visited[s.name] = true
+			w.walk(frag.selections, w.schema.Types[frag.typeCondition], root)
+			delete(w.visited, s.name)
+		}
+	}
+}
+
+func (w *cachePolicyWalker) walkField(f *field, parent types.NamedType, root bool) {
+	if f.name == "__typename" {
+		return
+	}
+	var fields types.FieldsDefinition
+	switch t := parent.(type) {
+	case *types.ObjectTypeDefinition:
+		fields = t.Fields
+	case *types.InterfaceTypeDefinition:
+		fields = t.Fields
+	}
+	def := fields.Get(f.name)
+	if def == nil {
+		// introspection or an invalid query, left uncached
+		w.restrict(0)
+		return
+	}
+	named := unwrapType(def.Type)
+	hint := def.Directives.Get("cacheControl")
+	composite := false
+	switch t := named.(type) {
+	case *types.ObjectTypeDefinition:
+		composite = true
+		if hint == nil {
+			hint = t.Directives.Get("cacheControl")
+		}
+	case *types.InterfaceTypeDefinition:
+		composite = true
+		if hint == nil {
+			hint = t.Directives.Get("cacheControl")
+		}
+	case *types.Union:
+		composite = true
+		if hint == nil {
+			hint = t.Directives.Get("cacheControl")
+		}
+	}
+	if hint != nil {
+		hasMaxAge := false
+		if value, ok := hint.Arguments.Get("maxAge"); ok && value != nil {
+			if maxAge, err := strconv.Atoi(value.String()); err == nil {
+				w.restrict(maxAge)
+				hasMaxAge = true
+			}
+		}
+		if value, ok := hint.Arguments.Get("scope"); ok && value != nil && value.String() == "PRIVATE" {
+			w.policy.private = true
+		}
+		if !hasMaxAge && (composite || root) {
+			w.restrict(0)
+		}
+	} else if composite || root {
+		w.restrict(0)
+	}
+	if composite {
+		w.walk(f.selections, named, false)
+	}
+}
+
+func cacheControlToCtx() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, cacheControlKey, &cachePolicy{})
+	}
+}
+
+func setCacheControl(ctx context.Context, policy cachePolicy) {
+	if p, ok := ctx.Value(cacheControlKey).(*cachePolicy); ok {
+		*p = policy
+	}
+}
+
+// cacheControlHeader Set the Cache-Control header of cached responses
+func cacheControlHeader() httptransport.ServerResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter) context.Context {
+		policy, ok := ctx.Value(cacheControlKey).(*cachePolicy)
+		if !ok || policy.maxAge <= 0 {
+			return ctx
+		}
+		scope := "public"
+		if policy.private {
+			scope = "private"
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, %s", policy.maxAge, scope))
+		return ctx
+	}
+}
+
+type lruCache struct {
+	entries *lru[lruEntry]
+}
+
+type lruEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewLRUCache Create an in memory ResponseCache keeping up to size
+// responses, evicting the least recently used
+func NewLRUCache(size int) ResponseCache {
+	return &lruCache{entries: newLRU[lruEntry](size)}
+}
+
+func (c *lruCache) Get(_ context.Context, key string) ([]byte, bool) {
+	entry, ok := c.entries.get(key)
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		c.entries.remove(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *lruCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	c.entries.add(key, lruEntry{value: value, expires: time.Now().Add(ttl)})
+}
@@ -0,0 +1,162 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const cachedSchema = `
+enum CacheControlScope { PUBLIC PRIVATE }
+directive @cacheControl(maxAge: Int, scope: CacheControlScope) on FIELD_DEFINITION | OBJECT | INTERFACE | UNION
+type Query {
+	products: [Product!]! @cacheControl(maxAge: 60)
+	me: Account @cacheControl(maxAge: 30, scope: PRIVATE)
+	now: String!
+}
+type Product @cacheControl(maxAge: 120) {
+	upc: String!
+	price: Int! @cacheControl(maxAge: 10)
+}
+type Account {
+	name: String!
+}`
+
+type cachedProduct struct {
+	UPC   string
+	Price int32
+}
+
+type cachedAccount struct {
+	Name string
+}
+
+type cachedResolver struct {
+	calls int
+}
+
+func (r *cachedResolver) Products() []*cachedProduct {
+	r.calls++
+	return []*cachedProduct{{UPC: "a", Price: 1}}
+}
+
+func (r *cachedResolver) Me(ctx context.Context) *cachedAccount {
+	r.calls++
+	subject, _ := claimsSubject(ctx)
+	return &cachedAccount{Name: "user " + subject}
+}
+
+func (r *cachedResolver) Now() string {
+	r.calls++
+	return time.Now().String()
+}
+
+func makeCachedRequests(t *testing.T, query string, authenticated bool, opts ...Option) (*cachedResolver, []*httptest.ResponseRecorder) {
+	resolver := &cachedResolver{}
+	opts = append(opts,
+		WithSchemaSources(SchemaString("schema.graphql", cachedSchema)),
+		WithResponseCache(NewLRUCache(10), false),
+	)
+	h, err := NewHandlers("", resolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := h.Handler()
+	var responses []*httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req, _ := CreateGraphqlRequest(query)
+		if authenticated {
+			req, _ = CreateGraphqlRequestWithAuthentication(query)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		responses = append(responses, resp)
+	}
+	return resolver, responses
+}
+
+func TestResponseCache_ShouldCacheByTheMinimumMaxAge(t *testing.T) {
+	tests := []struct {
+		query        string
+		cacheControl string
+	}{
+		{"{ products { upc } }", "max-age=60, public"},
+		{"{ products { upc price } }", "max-age=10, public"},
+	}
+	for _, test := range tests {
+		//Act
+		resolver, responses := makeCachedRequests(t, test.query, false)
+
+		//Assert
+		if resolver.calls != 1 {
+			t.Errorf("Should have resolved %s once and resolved %d times\n", test.query, resolver.calls)
+		}
+		for _, resp := range responses {
+			if cacheControl := resp.Header().Get("Cache-Control"); cacheControl != test.cacheControl {
+				t.Errorf("Should have returned Cache-Control %s for %s and returned %s\n", test.cacheControl, test.query, cacheControl)
+			}
+		}
+		if responses[0].Body.String() != responses[1].Body.String() {
+			t.Errorf("Should have returned the same response and returned %s and %s\n", responses[0].Body, responses[1].Body)
+		}
+	}
+}
+
+func TestResponseCache_ShouldNotCacheFieldsWithoutHints(t *testing.T) {
+	//Act
+	resolver, responses := makeCachedRequests(t, "{ now products { upc } }", false)
+
+	//Assert
+	if resolver.calls != 4 {
+		t.Errorf("Should have resolved every request and resolved %d times\n", resolver.calls)
+	}
+	if cacheControl := responses[1].Header().Get("Cache-Control"); cacheControl != "" {
+		t.Errorf("Should have returned no Cache-Control and returned %s\n", cacheControl)
+	}
+}
+
+func TestResponseCache_ShouldCachePrivateResponsesBySubject(t *testing.T) {
+	//Arrange
+	setup()
+	UserID = 7
+	auth := WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} })
+
+	//Act
+	anonymous, _ := makeCachedRequests(t, "{ me { name } }", false, auth, WithAuthBlacklist("me"))
+	resolver, responses := makeCachedRequests(t, "{ me { name } }", true, auth)
+
+	//Assert
+	if anonymous.calls != 2 {
+		t.Errorf("Should not have cached private responses without subject and resolved %d times\n", anonymous.calls)
+	}
+	if resolver.calls != 1 || !strings.Contains(responses[1].Body.String(), "user 7") {
+		t.Errorf("Should have cached the response of the subject and resolved %d times, returning %s\n", resolver.calls, responses[1].Body)
+	}
+	if cacheControl := responses[1].Header().Get("Cache-Control"); cacheControl != "max-age=30, private" {
+		t.Errorf("Should have returned a private Cache-Control and returned %s\n", cacheControl)
+	}
+}
+
+func TestLRUCache_ShouldEvictTheLeastRecentlyUsed(t *testing.T) {
+	//Arrange
+	ctx := context.Background()
+	cache := NewLRUCache(2)
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "expired", []byte("3"), -time.Second)
+
+	//Act
+	_, hasA := cache.Get(ctx, "a")
+	_, hasB := cache.Get(ctx, "b")
+	_, hasExpired := cache.Get(ctx, "expired")
+
+	//Assert
+	if !hasA || hasB || hasExpired {
+		t.Errorf("Should have kept only a and returned a=%v b=%v expired=%v\n", hasA, hasB, hasExpired)
+	}
+}
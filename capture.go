@@ -0,0 +1,192 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const (
+	captureHeadersKey contextKey = "captureHeaders"
+	capturePendingKey contextKey = "capturePending"
+)
+
+// CapturedRequest A request and its response, one json per line of the
+// capture, read back by ReadCaptured
+type CapturedRequest struct {
+	Time          time.Time         `json:"time"`
+	RequestID     string            `json:"request_id,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Query         string            `json:"query"`
+	OperationName string            `json:"operationName,omitempty"`
+	Variables     json.RawMessage   `json:"variables,omitempty"`
+	Response      json.RawMessage   `json:"response"`
+	// Status The http status of the response
+	Status int `json:"status,omitempty"`
+	// Took In nanoseconds, from the request to the response written by
+	// Handlers.Handler, as replaying measures it, or by the service alone
+	// when it's used without Handlers
+	Took time.Duration `json:"took"`
+}
+
+// pendingCapture The request captured by the service, written by
+// captureService.timed once the response is
+type pendingCapture struct {
+	captured *CapturedRequest
+}
+
+type captureService struct {
+	Service
+	mu       sync.Mutex
+	w        io.Writer
+	redactor *redactor
+	logger   log.Logger
+}
+
+// NewCaptureService Create a service writing every request and its response
+// to w as json lines, with the values of redaction and the @sensitive ones
+// omitted. Failures to write are logged in logger.
+func NewCaptureService(w io.Writer, s Service, redaction Redaction, logger log.Logger) Service {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &captureService{Service: s, w: w, redactor: newRedactor(redaction), logger: logger}
+}
+
+// AddCapture Write every request, with its headers listed in headers, and
+// its response to w as json lines, see NewCaptureService
+func (h *Handlers) AddCapture(w io.Writer, headers []string) {
+	h.captureWriter = w
+	h.captureHeaders = append(h.captureHeaders, headers...)
+}
+
+func (h *Handlers) addCapture() {
+	if h.captureWriter == nil {
+		return
+	}
+	h.capture = NewCaptureService(h.captureWriter, h.service, h.logRedaction, h.logger).(*captureService)
+	h.service = h.capture
+	if len(h.captureHeaders) > 0 {
+		h.AddServerOptions(httptransport.ServerBefore(captureHeadersToCtx(h.captureHeaders)))
+	}
+}
+
+func captureHeadersToCtx(names []string) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		headers := make(map[string]string)
+		for _, name := range names {
+			if value := r.Header.Get(name); value != "" {
+				headers[http.CanonicalHeaderKey(name)] = value
+			}
+		}
+		return context.WithValue(ctx, captureHeadersKey, headers)
+	}
+}
+
+func (s *captureService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	begin := time.Now()
+	res := s.Service.Exec(ctx, req)
+	if res == nil {
+		return res
+	}
+	var sensitive sensitiveValues
+	if schema := astSchemaOf(ctx, s.Service); schema != nil {
+		sensitive = sensitivePaths(ctx, schema, req)
+	}
+	captured := CapturedRequest{
+		Time:          begin.UTC(),
+		Query:         sensitive.redactQuery(req.Query),
+		OperationName: req.OperationName,
+		Took:          time.Since(begin),
+	}
+	captured.RequestID, _ = ctx.Value(httptransport.ContextKeyRequestXRequestID).(string)
+	captured.Headers, _ = ctx.Value(captureHeadersKey).(map[string]string)
+	captured.Status = http.StatusOK
+	if graphqlResponseNegotiated(ctx) {
+		// replayed negotiating the same media type, and so the same status
+		captured.Headers = withHeader(captured.Headers, "Accept", GraphqlResponseMediaType)
+		captured.Status = graphqlResponseStatus(res)
+	}
+	if req.Variables != nil {
+		if variables, err := json.Marshal(req.Variables); err == nil {
+			captured.Variables = sensitive.redactVariables(s.redactor, variables)
+		}
+	}
+	redacted := *res
+	if len(res.Data) > 0 {
+		redacted.Data = sensitive.redactResponse(ctx, s.redactor, req, res.Data)
+	}
+	response, err := json.Marshal(&redacted)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "capture failed", "x-req-id", captured.RequestID, "error", err)
+		return res
+	}
+	captured.Response = response
+	if pending, ok := ctx.Value(capturePendingKey).(*pendingCapture); ok {
+		pending.captured = &captured
+		return res
+	}
+	s.record(captured)
+	return res
+}
+
+// timed Time the captured requests from their arrival to their response
+// written by next, writing them once answered
+func (s *captureService) timed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pending := &pendingCapture{}
+		begin := time.Now()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), capturePendingKey, pending)))
+		if pending.captured == nil {
+			return
+		}
+		captured := *pending.captured
+		captured.Time, captured.Took = begin.UTC(), time.Since(begin)
+		s.record(captured)
+	})
+}
+
+// record Write captured, logging the failures
+func (s *captureService) record(captured CapturedRequest) {
+	if err := s.write(captured); err != nil {
+		level.Error(s.logger).Log("msg", "capture failed", "x-req-id", captured.RequestID, "error", err)
+	}
+}
+
+// Redacted Report if values of the request were omitted from the capture,
+// so it can't be sent again as it was
+func (c CapturedRequest) Redacted() bool {
+	return bytes.Contains(c.Variables, []byte(omitted)) || strings.Contains(c.Query, strconv.Quote(omitted))
+}
+
+// withHeader Return a copy of headers with name set to value
+func withHeader(headers map[string]string, name, value string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for key, v := range headers {
+		copied[key] = v
+	}
+	copied[name] = value
+	return copied
+}
+
+func (s *captureService) write(captured CapturedRequest) error {
+	line, err := json.Marshal(captured)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,111 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func captureHandler(t *testing.T, resolver interface{}, opts ...Option) http.Handler {
+	opts = append(opts,
+		WithSchemaSources(SchemaString("schema.graphql", sensitiveSchema)),
+		WithSchemaOptions(graphql.UseFieldResolvers()),
+	)
+	h, err := NewHandlers("", resolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.Handler()
+}
+
+func serveCaptured(handler http.Handler, body string) {
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Client", "tests")
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestHandlers_AddCapture(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	handler := captureHandler(t, &sensitiveResolver{}, WithCapture(&buf, "X-Client"))
+
+	//Act
+	serveCaptured(handler, auditedMutation)
+
+	//Assert
+	var captured CapturedRequest
+	if err := json.Unmarshal(buf.Bytes(), &captured); err != nil {
+		t.Fatal(err)
+	}
+	if captured.OperationName != "" || !strings.HasPrefix(captured.Query, "mutation Pay") {
+		t.Errorf("Should have captured the query and returned %+v\n", captured)
+	}
+	if len(captured.Headers) != 1 || captured.Headers["X-Client"] != "tests" {
+		t.Errorf("Should have captured only the chosen headers and returned %v\n", captured.Headers)
+	}
+	if strings.Contains(string(captured.Variables), "4111") || !strings.Contains(string(captured.Variables), "john") {
+		t.Errorf("Should have captured the redacted variables and returned %s\n", captured.Variables)
+	}
+	if want := `{"data":{"pay":{"name":"john"}}}`; string(captured.Response) != want {
+		t.Errorf("Should have captured the response %v and returned %s\n", want, captured.Response)
+	}
+	if captured.Took <= 0 {
+		t.Errorf("Should have captured how long it took and returned %v\n", captured.Took)
+	}
+	if captured.Status != http.StatusOK {
+		t.Errorf("Should have captured the status and returned %v\n", captured.Status)
+	}
+}
+
+func TestHandlers_AddCapture_ShouldTimeTheWholeRequest(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	slowBefore := httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+		time.Sleep(20 * time.Millisecond)
+		return ctx
+	})
+	handler := captureHandler(t, &sensitiveResolver{}, WithCapture(&buf), WithServerOptions(slowBefore))
+
+	//Act
+	serveCaptured(handler, `{"query":"{ account { name } }"}`)
+
+	//Assert
+	var captured CapturedRequest
+	if err := json.Unmarshal(buf.Bytes(), &captured); err != nil {
+		t.Fatal(err)
+	}
+	if captured.Took < 20*time.Millisecond {
+		t.Errorf("Should have timed the request as replaying does and returned %v\n", captured.Took)
+	}
+}
+
+func TestHandlers_AddCapture_WithGraphqlResponseMediaType(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	handler := captureHandler(t, &sensitiveResolver{}, WithCapture(&buf), WithGraphqlResponseMediaType())
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ unknown }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", GraphqlResponseMediaType)
+
+	//Act
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	var captured CapturedRequest
+	if err := json.Unmarshal(buf.Bytes(), &captured); err != nil {
+		t.Fatal(err)
+	}
+	if captured.Status != http.StatusBadRequest || captured.Headers["Accept"] != GraphqlResponseMediaType {
+		t.Errorf("Should have captured the status and the media type negotiated and returned %v %v\n", captured.Status, captured.Headers)
+	}
+}
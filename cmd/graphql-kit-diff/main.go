@@ -0,0 +1,75 @@
+// Command graphql-kit-diff compares two graphql schemas and reports the
+// changes classified as breaking, dangerous or safe. It exits with 1 when
+// there is a breaking change and with 2 when a schema can't be read.
+//
+//	graphql-kit-diff [-json] [-safe] old.graphql new.graphql
+//
+// Each schema may be a file, a directory of .graphql files or a glob.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	graphqlkit "github.com/rodrigobotelho/graphql-kit"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the changes as json")
+	showSafe := flag.Bool("safe", false, "also print safe changes")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: graphql-kit-diff [-json] [-safe] old.graphql new.graphql")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	changes, err := graphqlkit.DiffSchemaSources(schemaSource(flag.Arg(0)), schemaSource(flag.Arg(1)))
+	if err != nil {
+		fail(err)
+	}
+	if !*showSafe {
+		var unsafe graphqlkit.SchemaChanges
+		for _, change := range changes {
+			if change.Severity != graphqlkit.SafeChange {
+				unsafe = append(unsafe, change)
+			}
+		}
+		changes = unsafe
+	}
+	if *asJSON {
+		if changes == nil {
+			changes = graphqlkit.SchemaChanges{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(changes)
+	} else {
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+	}
+	if changes.Breaking() {
+		os.Exit(1)
+	}
+}
+
+func schemaSource(name string) graphqlkit.SchemaSource {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return graphqlkit.SchemaDir(name)
+	}
+	if strings.ContainsAny(name, "*?[") {
+		return graphqlkit.SchemaGlob(name)
+	}
+	return graphqlkit.SchemaFile(name)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "graphql-kit-diff:", err)
+	os.Exit(2)
+}
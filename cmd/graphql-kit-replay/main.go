@@ -0,0 +1,133 @@
+// Command graphql-kit-replay sends the requests captured with WithCapture
+// again to a graphql endpoint and reports the responses that changed and the
+// requests that got slower. The requests with values omitted from the
+// capture are skipped, they would be sent with the omitted values, and so are
+// the mutations unless -mutations is given. It exits
+// with 1 when a response or its status changed or a request got slower and
+// with 2 when the capture can't be read.
+//
+//	graphql-kit-replay [-H "Name: value"]... [-slower 2] [-all] [-mutations] url [capture.jsonl]
+//
+// The capture is read from the standard input when no file is given.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	graphqlkit "github.com/rodrigobotelho/graphql-kit"
+)
+
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(map[string]string(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	name, content, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header %q must be Name: value", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(content)
+	return nil
+}
+
+// headersReplayer Replay with some headers replaced, e.g. a fresh token
+type headersReplayer struct {
+	graphqlkit.Replayer
+	headers headerFlags
+}
+
+func (r *headersReplayer) Replay(ctx context.Context, captured graphqlkit.CapturedRequest) graphqlkit.ReplayResult {
+	if len(r.headers) > 0 {
+		headers := make(map[string]string, len(captured.Headers)+len(r.headers))
+		for name, value := range captured.Headers {
+			headers[name] = value
+		}
+		for name, value := range r.headers {
+			headers[name] = value
+		}
+		captured.Headers = headers
+	}
+	return r.Replayer.Replay(ctx, captured)
+}
+
+func main() {
+	headers := headerFlags{}
+	flag.Var(headers, "H", "header sent with every request, replacing the captured one")
+	slower := flag.Float64("slower", 2, "report requests taking more than this times the captured duration, never when 0")
+	all := flag.Bool("all", false, "also print the unchanged requests")
+	mutations := flag.Bool("mutations", false, "also replay the mutations, running them again on the target")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), `usage: graphql-kit-replay [-H "Name: value"]... [-slower 2] [-all] [-mutations] url [capture.jsonl]`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var capture io.Reader = os.Stdin
+	if flag.NArg() == 2 {
+		file, err := os.Open(flag.Arg(1))
+		if err != nil {
+			fail(err)
+		}
+		defer file.Close()
+		capture = file
+	}
+	var opts []graphqlkit.ReplayOption
+	if *mutations {
+		opts = append(opts, graphqlkit.ReplayMutations())
+	}
+	replayer := &headersReplayer{graphqlkit.NewURLReplayer(nil, flag.Arg(0), opts...), headers}
+	var total, changed, slowed, skipped int
+	err := graphqlkit.Replay(context.Background(), capture, replayer, func(result graphqlkit.ReplayResult) {
+		total++
+		name := result.Captured.OperationName
+		if name == "" {
+			name = strings.Join(strings.Fields(result.Captured.Query), " ")
+		}
+		slow := *slower > 0 && result.Err == nil &&
+			float64(result.Took) > *slower*float64(result.Captured.Took)
+		switch {
+		case result.Skipped != "":
+			skipped++
+			fmt.Printf("SKIP %s: %s\n", name, result.Skipped)
+		case result.Err != nil:
+			changed++
+			fmt.Printf("FAIL %s: %v\n", name, result.Err)
+		case len(result.Diffs) > 0:
+			changed++
+			fmt.Printf("DIFF %s\n", name)
+			for _, diff := range result.Diffs {
+				fmt.Printf("  %s\n", diff)
+			}
+		case *all:
+			fmt.Printf("OK   %s\n", name)
+		}
+		if slow {
+			slowed++
+			fmt.Printf("SLOW %s: took %v, captured %v\n", name,
+				result.Took.Round(time.Microsecond), result.Captured.Took.Round(time.Microsecond))
+		}
+	})
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("%d requests, %d changed, %d slower, %d skipped\n", total, changed, slowed, skipped)
+	if changed > 0 || slowed > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "graphql-kit-replay:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,195 @@
+package graphqlkit
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+const compressionKey contextKey = "compression"
+
+// Compressor Compress the responses with an encoding, e.g. brotli
+type Compressor interface {
+	// Encoding The name of the encoding in Accept-Encoding and
+	// Content-Encoding, e.g. br
+	Encoding() string
+	// NewWriter Return a writer compressing to w, flushed by Close
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+type pooledCompressor struct {
+	encoding string
+	pool     sync.Pool
+}
+
+type pooledWriter struct {
+	writer interface {
+		io.WriteCloser
+		Reset(io.Writer)
+	}
+	pool *sync.Pool
+}
+
+func (w *pooledWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *pooledWriter) Close() error {
+	err := w.writer.Close()
+	w.pool.Put(w.writer)
+	return err
+}
+
+func (c *pooledCompressor) Encoding() string {
+	return c.encoding
+}
+
+func (c *pooledCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	writer := c.pool.Get().(interface {
+		io.WriteCloser
+		Reset(io.Writer)
+	})
+	writer.Reset(w)
+	return &pooledWriter{writer: writer, pool: &c.pool}, nil
+}
+
+// GzipCompressor Compress the responses with gzip
+func GzipCompressor() Compressor {
+	return &pooledCompressor{encoding: "gzip", pool: sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}}}
+}
+
+// DeflateCompressor Compress the responses with deflate, which in http is
+// zlib wrapped data
+func DeflateCompressor() Compressor {
+	return &pooledCompressor{encoding: "deflate", pool: sync.Pool{New: func() interface{} {
+		return zlib.NewWriter(io.Discard)
+	}}}
+}
+
+// compression The compressors, by preference, and the smallest response
+// compressed
+type compression struct {
+	compressors []Compressor
+	minSize     int
+}
+
+// negotiatedCompression How the response of a request is compressed
+type negotiatedCompression struct {
+	compressor Compressor
+	minSize    int
+}
+
+// AddCompression Compress the responses of at least minSize bytes with the
+// encoding preferred by Accept-Encoding, among compressors, gzip and deflate
+func (h *Handlers) AddCompression(minSize int, compressors ...Compressor) {
+	c := &compression{minSize: minSize}
+	c.compressors = append(c.compressors, compressors...)
+	c.compressors = append(c.compressors, GzipCompressor(), DeflateCompressor())
+	h.compression = c
+}
+
+func (h *Handlers) addCompression() {
+	if h.compression == nil {
+		return
+	}
+	h.AddServerOptions(httptransport.ServerBefore(compressionToCtx(h.compression)))
+}
+
+func compressionToCtx(c *compression) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, compressionKey, &negotiatedCompression{
+			compressor: c.negotiate(r.Header.Get("Accept-Encoding")),
+			minSize:    c.minSize,
+		})
+	}
+}
+
+// negotiate Return the compressor with the highest quality in accept, the
+// first one on ties, nil if none is acceptable
+func (c *compression) negotiate(accept string) Compressor {
+	qualities := acceptQualities(accept)
+	wildcard, ok := qualities["*"]
+	if !ok {
+		wildcard = -1
+	}
+	var best Compressor
+	bestQuality := 0.0
+	for _, compressor := range c.compressors {
+		quality, ok := qualities[compressor.Encoding()]
+		if !ok {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = compressor, quality
+		}
+	}
+	return best
+}
+
+// compressedWriter Return the writer of a response of size bytes to w,
+// compressing it if negotiated for the request of ctx and long enough, and
+// the func flushing it
+func compressedWriter(ctx context.Context, w http.ResponseWriter, size int) (io.Writer, func() error) {
+	flush := func() error { return nil }
+	negotiated, ok := ctx.Value(compressionKey).(*negotiatedCompression)
+	if !ok {
+		return w, flush
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	if negotiated.compressor == nil || size < negotiated.minSize {
+		return w, flush
+	}
+	writer, err := negotiated.compressor.NewWriter(w)
+	if err != nil {
+		return w, flush
+	}
+	w.Header().Set("Content-Encoding", negotiated.compressor.Encoding())
+	w.Header().Del("Content-Length")
+	return writer, writer.Close
+}
+
+// acceptQualities Return the quality of each value of an Accept or
+// Accept-Encoding header, lower cased
+func acceptQualities(accept string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			name, q, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(q, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[value] = quality
+	}
+	return qualities
+}
+
+// decompressedBody Return the body of r without its Content-Encoding
+func decompressedBody(r *http.Request) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r.Body)
+	case "deflate":
+		return zlib.NewReader(r.Body)
+	}
+	return nil, errUnsupportedEncoding
+}
@@ -0,0 +1,198 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type nopCompressor string
+
+func (c nopCompressor) Encoding() string {
+	return string(c)
+}
+
+func (c nopCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func serveCompressed(t *testing.T, body io.Reader, headers map[string]string, opts ...Option) *httptest.ResponseRecorder {
+	setup()
+	opts = append(opts, WithSchemaSources(SchemaString("schema.graphql", schema)))
+	h, err := NewHandlers("", &queryResolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "/graphql", body)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp := httptest.NewRecorder()
+	h.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCompression_Negotiate(t *testing.T) {
+	c := &compression{compressors: []Compressor{nopCompressor("br"), GzipCompressor(), DeflateCompressor()}}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"br, gzip", "br"},
+		{"GZIP;Q=0.8, br;q=0", "gzip"},
+		{"*", "br"},
+		{"*;q=0.1, gzip;q=0", "br"},
+		{"*;q=0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			got := ""
+			if compressor := c.negotiate(tt.accept); compressor != nil {
+				got = compressor.Encoding()
+			}
+			if got != tt.want {
+				t.Errorf("Should have chosen %q and returned %q\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandlers_WithCompression_ShouldCompressTheResponse(t *testing.T) {
+	//Arrange
+	body := `{"query":"{ anyMethod(param: [1]) }"}`
+
+	//Act
+	resp := serveCompressed(t, strings.NewReader(body), map[string]string{"Accept-Encoding": "gzip"}, WithCompression(0))
+
+	//Assert
+	if resp.Header().Get("Content-Encoding") != "gzip" || resp.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Should have compressed with gzip and returned %v\n", resp.Header())
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, _ := ioutil.ReadAll(reader)
+	if !strings.Contains(string(decompressed), `"data"`) {
+		t.Errorf("Should have compressed the response and returned %s\n", decompressed)
+	}
+}
+
+func TestHandlers_WithCompression_ShouldNotCompressSmallResponses(t *testing.T) {
+	//Arrange
+	body := `{"query":"{ anyMethod(param: [1]) }"}`
+
+	//Act
+	resp := serveCompressed(t, strings.NewReader(body), map[string]string{"Accept-Encoding": "gzip"}, WithCompression(1024))
+
+	//Assert
+	if resp.Header().Get("Content-Encoding") != "" || !strings.Contains(resp.Body.String(), `"data"`) {
+		t.Errorf("Should have answered uncompressed and returned %v %s\n", resp.Header(), resp.Body.String())
+	}
+	if resp.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Should have varied by Accept-Encoding and returned %v\n", resp.Header())
+	}
+}
+
+func TestHandlers_WithCompression_ShouldPreferTheAddedCompressors(t *testing.T) {
+	//Arrange
+	body := `{"query":"{ anyMethod(param: [1]) }"}`
+
+	//Act
+	resp := serveCompressed(t, strings.NewReader(body), map[string]string{"Accept-Encoding": "gzip, br"},
+		WithCompression(0, nopCompressor("br")))
+
+	//Assert
+	if resp.Header().Get("Content-Encoding") != "br" || !strings.Contains(resp.Body.String(), `"data"`) {
+		t.Errorf("Should have compressed with br and returned %v %s\n", resp.Header(), resp.Body.String())
+	}
+}
+
+func TestHandlers_GzipRequestBody_ShouldBeDecompressed(t *testing.T) {
+	//Arrange
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	writer.Write([]byte(`{"query":"{ anyMethod(param: [1]) }"}`))
+	writer.Close()
+
+	//Act
+	resp := serveCompressed(t, &body, map[string]string{"Content-Encoding": "gzip"})
+
+	//Assert
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"data"`) {
+		t.Errorf("Should have decompressed the request and returned %v %s\n", resp.Code, resp.Body.String())
+	}
+}
+
+func TestHandlers_DeflateRequestBody_ShouldBeDecompressedAsZlib(t *testing.T) {
+	//Arrange
+	var body bytes.Buffer
+	writer := zlib.NewWriter(&body)
+	writer.Write([]byte(`{"query":"{ anyMethod(param: [1]) }"}`))
+	writer.Close()
+
+	//Act
+	resp := serveCompressed(t, &body, map[string]string{"Content-Encoding": "deflate", "Accept-Encoding": "deflate"},
+		WithCompression(0))
+
+	//Assert
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("Should have decompressed the request and compressed the response and returned %v %v %s\n",
+			resp.Code, resp.Header(), resp.Body.String())
+	}
+	reader, err := zlib.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, _ := ioutil.ReadAll(reader)
+	if !strings.Contains(string(decompressed), `"data"`) {
+		t.Errorf("Should have compressed the response with zlib and returned %s\n", decompressed)
+	}
+}
+
+func TestHandlers_CompressedRequestBody_Errors(t *testing.T) {
+	var large bytes.Buffer
+	writer := gzip.NewWriter(&large)
+	writer.Write([]byte(`{"query":"{ anyMethod(param: [1]) }"}` + strings.Repeat(" ", 100)))
+	writer.Close()
+	tests := []struct {
+		name     string
+		body     io.Reader
+		encoding string
+		opts     []Option
+		code     int
+	}{
+		{"Unsupported encoding", strings.NewReader(`{}`), "compress", nil, http.StatusUnsupportedMediaType},
+		{"Invalid gzip", strings.NewReader(`not gzip`), "gzip", nil, http.StatusBadRequest},
+		{"Invalid zlib", strings.NewReader(`not zlib`), "deflate", nil, http.StatusBadRequest},
+		{"Too large once decompressed", &large, "gzip", []Option{WithMaxBodyBytes(64)}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serveCompressed(t, tt.body, map[string]string{"Content-Encoding": tt.encoding}, tt.opts...)
+			if resp.Code != tt.code {
+				t.Errorf("Should have answered %v and returned %v %v\n", tt.code, resp.Code, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Package dataloader Batch and cache the loads of the resolvers of a request,
+// so resolving a list doesn't make a query for each of its items
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatchLength The batch function didn't return a value for each key
+var ErrBatchLength = errors.New("dataloader: batch function returned a wrong number of values")
+
+// BatchFunc Load the values of keys, in the same order. errs is nil when all
+// of them loaded, has a single error failing all of them or an error (or nil)
+// for each key.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (values []V, errs []error)
+
+// Observer Receive the size and duration of each batch
+type Observer func(size int, took time.Duration)
+
+// Observable A loader reporting its batches to an Observer
+type Observable interface {
+	Observe(o Observer)
+}
+
+// Option Configure a Loader
+type Option func(*config)
+
+type config struct {
+	maxBatch int
+	wait     time.Duration
+	cache    bool
+}
+
+// WithMaxBatch Dispatch a batch as soon as it has size keys, no limit when 0
+func WithMaxBatch(size int) Option {
+	return func(c *config) {
+		c.maxBatch = size
+	}
+}
+
+// WithWait Wait up to d for more keys before dispatching a batch, 1ms by
+// default
+func WithWait(d time.Duration) Option {
+	return func(c *config) {
+		c.wait = d
+	}
+}
+
+// WithoutCache Load the keys again in every batch instead of keeping their
+// values for the life of the loader
+func WithoutCache() Option {
+	return func(c *config) {
+		c.cache = false
+	}
+}
+
+// Loader Collect the keys loaded in a wait window and load them with one
+// call of its batch function, keeping the values by key. A loader is meant to
+// live for a single request.
+type Loader[K comparable, V any] struct {
+	config
+	batch    BatchFunc[K, V]
+	observer Observer
+	mu       sync.Mutex
+	results  map[K]*result[V]
+	pending  *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results []*result[V]
+	index   map[K]int
+	timer   *time.Timer
+}
+
+// New Create a Loader loading with batch
+func New[K comparable, V any](batch BatchFunc[K, V], opts ...Option) *Loader[K, V] {
+	l := &Loader[K, V]{
+		config:  config{wait: time.Millisecond, cache: true},
+		batch:   batch,
+		results: make(map[K]*result[V]),
+	}
+	for _, opt := range opts {
+		opt(&l.config)
+	}
+	return l
+}
+
+// Observe Report the batches of the loader to o
+func (l *Loader[K, V]) Observe(o Observer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.observer = o
+}
+
+// Load Return the value of key, waiting for the batch loading it
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return l.await(ctx, l.enqueue(ctx, key))
+}
+
+// LoadMany Return the values of keys, loaded in as few batches as possible.
+// errs is nil when all of them loaded.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, []error) {
+	results := make([]*result[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.enqueue(ctx, key)
+	}
+	values := make([]V, len(keys))
+	var errs []error
+	for i, r := range results {
+		var err error
+		values[i], err = l.await(ctx, r)
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(keys))
+			}
+			errs[i] = err
+		}
+	}
+	return values, errs
+}
+
+// Prime Keep value for key, unless it is already loaded
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.results[key]; ok {
+		return
+	}
+	r := &result[V]{done: make(chan struct{}), value: value}
+	close(r.done)
+	l.results[key] = r
+}
+
+// Clear Forget the value of key, loading it again next time
+func (l *Loader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.results, key)
+}
+
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) *result[V] {
+	l.mu.Lock()
+	if r, ok := l.results[key]; ok {
+		l.mu.Unlock()
+		return r
+	}
+	b := l.pending
+	if b == nil {
+		b = &batch[K, V]{ctx: ctx, index: make(map[K]int)}
+		b.timer = time.AfterFunc(l.wait, func() { l.dispatchPending(b) })
+		l.pending = b
+	}
+	if i, ok := b.index[key]; ok {
+		l.mu.Unlock()
+		return b.results[i]
+	}
+	r := &result[V]{done: make(chan struct{})}
+	b.index[key] = len(b.keys)
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+	if l.cache {
+		l.results[key] = r
+	}
+	full := l.maxBatch > 0 && len(b.keys) >= l.maxBatch
+	if full {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+	if full {
+		b.timer.Stop()
+		go l.dispatch(b)
+	}
+	return r
+}
+
+func (l *Loader[K, V]) await(ctx context.Context, r *result[V]) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatchPending Dispatch b when its wait window ends, unless it was
+// already dispatched for being full
+func (l *Loader[K, V]) dispatchPending(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+	l.dispatch(b)
+}
+
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	begin := time.Now()
+	values, errs := l.call(b)
+	l.mu.Lock()
+	observer := l.observer
+	l.mu.Unlock()
+	if observer != nil {
+		observer(len(b.keys), time.Since(begin))
+	}
+	l.mu.Lock()
+	for i, r := range b.results {
+		switch {
+		case len(errs) == 1 && (len(b.keys) != 1 || errs[0] != nil):
+			// one error for the whole batch, unless it's the nil error of
+			// its only key
+			r.err = errs[0]
+		case errs != nil && len(errs) != len(b.keys):
+			r.err = ErrBatchLength
+		case errs != nil && errs[i] != nil:
+			r.err = errs[i]
+		case len(values) != len(b.keys):
+			r.err = ErrBatchLength
+		default:
+			r.value = values[i]
+		}
+		if r.err != nil && l.results[b.keys[i]] == r {
+			// failed loads are tried again by the next batch
+			delete(l.results, b.keys[i])
+		}
+		close(r.done)
+	}
+	l.mu.Unlock()
+}
+
+// call Run the batch function, failing the batch if it panics
+func (l *Loader[K, V]) call(b *batch[K, V]) (values []V, errs []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			values, errs = nil, []error{fmt.Errorf("dataloader: batch function panicked: %v", r)}
+		}
+	}()
+	return l.batch(b.ctx, b.keys)
+}
+
+type contextKey string
+
+const loadersKey contextKey = "dataloaders"
+
+// NewContext Attach the loaders of a request, by name, to ctx
+func NewContext(ctx context.Context, loaders map[string]interface{}) context.Context {
+	return context.WithValue(ctx, loadersKey, loaders)
+}
+
+// For Return the loader attached to ctx with name, nil if there is none or it
+// loads other types
+func For[K comparable, V any](ctx context.Context, name string) *Loader[K, V] {
+	loaders, _ := ctx.Value(loadersKey).(map[string]interface{})
+	loader, _ := loaders[name].(*Loader[K, V])
+	return loader
+}
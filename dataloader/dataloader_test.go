@@ -0,0 +1,192 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingBatch struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (b *recordingBatch) load(ctx context.Context, keys []int) ([]string, []error) {
+	b.mu.Lock()
+	b.batches = append(b.batches, append([]int{}, keys...))
+	b.mu.Unlock()
+	values := make([]string, len(keys))
+	var errs []error
+	for i, key := range keys {
+		if key < 0 {
+			if errs == nil {
+				errs = make([]error, len(keys))
+			}
+			errs[i] = errors.New("negative key")
+			continue
+		}
+		values[i] = string(rune('a' + key))
+	}
+	return values, errs
+}
+
+func loadConcurrently(l *Loader[int, string], keys ...int) ([]string, []error) {
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(context.Background(), key)
+		}(i, key)
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func TestLoader_Batches(t *testing.T) {
+	//Arrange
+	b := &recordingBatch{}
+	l := New(b.load, WithWait(10*time.Millisecond))
+
+	//Act
+	values, _ := loadConcurrently(l, 0, 1, 2, 1)
+
+	//Assert
+	if len(b.batches) != 1 || len(b.batches[0]) != 3 {
+		t.Errorf("Should have loaded the distinct keys in one batch and returned %v\n", b.batches)
+	}
+	if values[0] != "a" || values[1] != "b" || values[2] != "c" || values[3] != "b" {
+		t.Errorf("Should have returned the values in order and returned %v\n", values)
+	}
+}
+
+func TestLoader_MaxBatch(t *testing.T) {
+	//Arrange
+	b := &recordingBatch{}
+	l := New(b.load, WithWait(10*time.Millisecond), WithMaxBatch(2))
+
+	//Act
+	values, errs := l.LoadMany(context.Background(), []int{0, 1, 2, 3, 4})
+
+	//Assert
+	if len(b.batches) != 3 {
+		t.Errorf("Should have split the keys in 3 batches and returned %v\n", b.batches)
+	}
+	if errs != nil || values[4] != "e" {
+		t.Errorf("Should have loaded all keys and returned %v %v\n", values, errs)
+	}
+}
+
+func TestLoader_Cache(t *testing.T) {
+	//Arrange
+	b := &recordingBatch{}
+	cached := New(b.load)
+	uncached := New(b.load, WithoutCache())
+	cached.Prime(9, "primed")
+
+	//Act
+	cached.Load(context.Background(), 1)
+	cached.Load(context.Background(), 1)
+	primed, _ := cached.Load(context.Background(), 9)
+	uncached.Load(context.Background(), 1)
+	uncached.Load(context.Background(), 1)
+
+	//Assert
+	if len(b.batches) != 3 {
+		t.Errorf("Should have loaded once with cache and twice without and returned %v\n", b.batches)
+	}
+	if primed != "primed" {
+		t.Errorf("Should have returned the primed value and returned %v\n", primed)
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+	//Arrange
+	b := &recordingBatch{}
+	l := New(b.load)
+	short := New(func(ctx context.Context, keys []int) ([]string, []error) {
+		return nil, nil
+	})
+	panicking := New(func(ctx context.Context, keys []int) ([]string, []error) {
+		panic("boom")
+	})
+
+	//Act
+	values, errs := loadConcurrently(l, 1, -1)
+	l.Load(context.Background(), -1)
+	_, shortErr := short.Load(context.Background(), 1)
+	_, panicErr := panicking.Load(context.Background(), 1)
+
+	//Assert
+	if values[0] != "b" || errs[0] != nil || errs[1] == nil {
+		t.Errorf("Should have failed only the negative key and returned %v %v\n", values, errs)
+	}
+	if len(b.batches) != 2 {
+		t.Errorf("Should have tried the failed key again and returned %v\n", b.batches)
+	}
+	if shortErr != ErrBatchLength {
+		t.Errorf("Should have failed for the missing values and returned %v\n", shortErr)
+	}
+	if panicErr == nil {
+		t.Errorf("Should have failed for the panic")
+	}
+}
+
+func TestLoader_SingleKeyWithErrorPerKey(t *testing.T) {
+	//Arrange
+	calls := 0
+	l := New(func(ctx context.Context, keys []int) ([]string, []error) {
+		calls++
+		return []string{"a"}, []error{nil}
+	})
+
+	//Act
+	value, err := l.Load(context.Background(), 0)
+	cached, _ := l.Load(context.Background(), 0)
+
+	//Assert
+	if value != "a" || err != nil || cached != "a" {
+		t.Errorf("Should have loaded the value of the only key and returned %q %v, then %q\n", value, err, cached)
+	}
+	if calls != 1 {
+		t.Errorf("Should have loaded the key once and loaded it %d times\n", calls)
+	}
+}
+
+func TestLoader_Observe(t *testing.T) {
+	//Arrange
+	b := &recordingBatch{}
+	l := New(b.load)
+	var sizes []int
+	l.Observe(func(size int, took time.Duration) {
+		sizes = append(sizes, size)
+	})
+
+	//Act
+	l.LoadMany(context.Background(), []int{1, 2})
+
+	//Assert
+	if len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("Should have observed one batch of 2 keys and returned %v\n", sizes)
+	}
+}
+
+func TestFor(t *testing.T) {
+	//Arrange
+	l := New((&recordingBatch{}).load)
+	ctx := NewContext(context.Background(), map[string]interface{}{"letters": l})
+
+	//Act
+	found := For[int, string](ctx, "letters")
+	otherTypes := For[string, string](ctx, "letters")
+	missing := For[int, string](context.Background(), "letters")
+
+	//Assert
+	if found != l || otherTypes != nil || missing != nil {
+		t.Errorf("Should have found only the loader with its name and types")
+	}
+}
@@ -0,0 +1,77 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	httptransport "github.com/go-kit/kit/transport/http"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/rodrigobotelho/graphql-kit/dataloader"
+)
+
+// LoaderFactory Create a loader, usually a *dataloader.Loader, for the request
+// of ctx
+type LoaderFactory func(ctx context.Context) interface{}
+
+type loaderMetrics struct {
+	batchSize     metrics.Histogram
+	batchDuration metrics.Histogram
+}
+
+// AddDataLoader Create a loader with factory for each request, found by the
+// resolvers with dataloader.For(ctx, name). With instrumenting the size and
+// duration of its batches are exported.
+func (h *Handlers) AddDataLoader(name string, factory LoaderFactory) {
+	if h.loaders == nil {
+		h.loaders = make(map[string]LoaderFactory)
+	}
+	h.loaders[name] = factory
+}
+
+func (h *Handlers) addDataLoaders() {
+	if len(h.loaders) == 0 {
+		return
+	}
+	var m *loaderMetrics
+	if h.namespace != "" {
+		m = &loaderMetrics{
+			batchSize: kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+				Namespace: h.namespace,
+				Subsystem: h.subsystem,
+				Name:      "dataloader_batch_size",
+				Help:      "Number of keys loaded by each batch.",
+			}, []string{"loader"}),
+			batchDuration: kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+				Namespace: h.namespace,
+				Subsystem: h.subsystem,
+				Name:      "dataloader_batch_duration_seconds",
+				Help:      "Total duration of the batches in seconds.",
+			}, []string{"loader"}),
+		}
+	}
+	h.AddServerOptions(httptransport.ServerBefore(dataLoadersToCtx(h.loaders, m)))
+}
+
+func dataLoadersToCtx(factories map[string]LoaderFactory, m *loaderMetrics) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		loaders := make(map[string]interface{}, len(factories))
+		for name, factory := range factories {
+			loader := factory(ctx)
+			if observable, ok := loader.(dataloader.Observable); ok && m != nil {
+				observable.Observe(m.observer(name))
+			}
+			loaders[name] = loader
+		}
+		return dataloader.NewContext(ctx, loaders)
+	}
+}
+
+func (m *loaderMetrics) observer(name string) dataloader.Observer {
+	return func(size int, took time.Duration) {
+		m.batchSize.With("loader", name).Observe(float64(size))
+		m.batchDuration.With("loader", name).Observe(took.Seconds())
+	}
+}
@@ -0,0 +1,85 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rodrigobotelho/graphql-kit/dataloader"
+)
+
+const loadedSchema = `
+type Query { posts: [Post!]! }
+type Post { title: String! author: Author! }
+type Author { name: String! }`
+
+type loadedAuthor struct {
+	Name string
+}
+
+type loadedPost struct {
+	title  string
+	author int
+}
+
+func (p *loadedPost) Title() string {
+	return p.title
+}
+
+func (p *loadedPost) Author(ctx context.Context) (*loadedAuthor, error) {
+	return dataloader.For[int, *loadedAuthor](ctx, "authors").Load(ctx, p.author)
+}
+
+type loadedResolver struct{}
+
+func (r *loadedResolver) Posts() []*loadedPost {
+	return []*loadedPost{{"a", 1}, {"b", 2}, {"c", 1}}
+}
+
+func TestHandlers_AddDataLoader(t *testing.T) {
+	//Arrange
+	var mu sync.Mutex
+	var batches [][]int
+	factory := func(ctx context.Context) interface{} {
+		return dataloader.New(func(ctx context.Context, ids []int) ([]*loadedAuthor, []error) {
+			mu.Lock()
+			batches = append(batches, ids)
+			mu.Unlock()
+			authors := make([]*loadedAuthor, len(ids))
+			for i, id := range ids {
+				authors[i] = &loadedAuthor{Name: strings.Repeat("x", id)}
+			}
+			return authors, nil
+		}, dataloader.WithWait(10*time.Millisecond))
+	}
+	h, err := NewHandlers("", &loadedResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", loadedSchema)),
+		WithDataLoader("authors", factory),
+		WithInstrumenting("dataloader_test", "graphql"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := h.Handler()
+
+	//Act
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		req, _ := CreateGraphqlRequest("{ posts { title author { name } } }")
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		bodies = append(bodies, resp.Body.String())
+	}
+
+	//Assert
+	want := `{"data":{"posts":[{"title":"a","author":{"name":"x"}},{"title":"b","author":{"name":"xx"}},{"title":"c","author":{"name":"x"}}]}}`
+	if bodies[0] != want {
+		t.Errorf("Should have resolved the authors and returned %v\n", bodies[0])
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Errorf("Should have loaded the authors once per request and returned %v\n", batches)
+	}
+}
@@ -0,0 +1,395 @@
+package graphqlkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// ChangeSeverity How a schema change affects existing clients
+type ChangeSeverity int
+
+const (
+	// SafeChange can't break any client
+	SafeChange ChangeSeverity = iota
+	// DangerousChange doesn't break valid queries but may change how clients
+	// behave, e.g. a new enum value they don't handle
+	DangerousChange
+	// BreakingChange makes queries that were valid fail
+	BreakingChange
+)
+
+func (s ChangeSeverity) String() string {
+	switch s {
+	case BreakingChange:
+		return "BREAKING"
+	case DangerousChange:
+		return "DANGEROUS"
+	}
+	return "SAFE"
+}
+
+// MarshalText Encode the severity by its name
+func (s ChangeSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SchemaChange A difference between two versions of a schema
+type SchemaChange struct {
+	Severity ChangeSeverity `json:"severity"`
+	// Path of the changed element, e.g. Query.user(id)
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (c SchemaChange) String() string {
+	return fmt.Sprintf("%-9s %s: %s", c.Severity, c.Path, c.Message)
+}
+
+// SchemaChanges The changes found by DiffSchemas
+type SchemaChanges []SchemaChange
+
+// Breaking Check if any change breaks existing clients
+func (c SchemaChanges) Breaking() bool {
+	for _, change := range c {
+		if change.Severity == BreakingChange {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffSchemas Compare two SDL schemas, returning the changes from oldSchema
+// to newSchema sorted by severity and path
+func DiffSchemas(oldSchema, newSchema string) (SchemaChanges, error) {
+	return DiffSchemaSources(SchemaString("old", oldSchema), SchemaString("new", newSchema))
+}
+
+// DiffSchemaSources Compare the schemas made of the documents of each source
+func DiffSchemaSources(oldSource, newSource SchemaSource) (SchemaChanges, error) {
+	parsed := make([]*types.Schema, 2)
+	for i, source := range []SchemaSource{oldSource, newSource} {
+		docs, err := LoadSchemaSources(source)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := ParseSchemaDocuments(docs, nil)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = schema.ASTSchema()
+	}
+	return diffSchemas(parsed[0], parsed[1]), nil
+}
+
+// DiffBaseline Compare the schema of the handler with the baseline SDL,
+// e.g. a schema file committed with the last release
+func (h *Handlers) DiffBaseline(baseline string) (SchemaChanges, error) {
+	service, ok := h.graphql.(*graphqlService)
+	if !ok {
+		return nil, errSchemaNotFound
+	}
+	sdl, _ := service.loaded().printed()
+	return DiffSchemas(baseline, sdl)
+}
+
+type schemaDiff struct {
+	changes SchemaChanges
+}
+
+func (d *schemaDiff) add(severity ChangeSeverity, path, format string, args ...interface{}) {
+	d.changes = append(d.changes, SchemaChange{severity, path, fmt.Sprintf(format, args...)})
+}
+
+func diffSchemas(oldSchema, newSchema *types.Schema) SchemaChanges {
+	d := &schemaDiff{}
+	for _, op := range []string{"query", "mutation", "subscription"} {
+		oldRoot, newRoot := oldSchema.EntryPointNames[op], newSchema.EntryPointNames[op]
+		if oldRoot != "" && oldRoot != newRoot {
+			d.add(BreakingChange, "schema."+op, "root type changed from %q to %q", oldRoot, newRoot)
+		}
+	}
+	for name, oldType := range oldSchema.Types {
+		if builtinTypes[name] || strings.HasPrefix(name, "__") {
+			continue
+		}
+		newType, ok := newSchema.Types[name]
+		if !ok {
+			d.add(BreakingChange, name, "%s removed", strings.ToLower(oldType.Kind()))
+			continue
+		}
+		if oldType.Kind() != newType.Kind() {
+			d.add(BreakingChange, name, "changed from %s to %s", oldType.Kind(), newType.Kind())
+			continue
+		}
+		d.diffType(oldType, newType)
+	}
+	for name, newType := range newSchema.Types {
+		if _, ok := oldSchema.Types[name]; !ok {
+			d.add(SafeChange, name, "%s added", strings.ToLower(newType.Kind()))
+		}
+	}
+	for name, oldDirective := range oldSchema.Directives {
+		newDirective, ok := newSchema.Directives[name]
+		if !ok {
+			d.add(BreakingChange, "@"+name, "directive removed")
+			continue
+		}
+		for _, loc := range oldDirective.Locations {
+			if !containsString(newDirective.Locations, loc) {
+				d.add(BreakingChange, "@"+name, "location %s removed", loc)
+			}
+		}
+		d.diffArguments("@"+name, oldDirective.Arguments, newDirective.Arguments)
+	}
+	for name := range newSchema.Directives {
+		if _, ok := oldSchema.Directives[name]; !ok {
+			d.add(SafeChange, "@"+name, "directive added")
+		}
+	}
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		if d.changes[i].Severity != d.changes[j].Severity {
+			return d.changes[i].Severity > d.changes[j].Severity
+		}
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return d.changes
+}
+
+func (d *schemaDiff) diffType(oldType, newType types.NamedType) {
+	switch oldType := oldType.(type) {
+	case *types.ObjectTypeDefinition:
+		newType := newType.(*types.ObjectTypeDefinition)
+		d.diffInterfaces(oldType.Name, oldType.Interfaces, newType.Interfaces)
+		d.diffFields(oldType.Name, oldType.Fields, newType.Fields)
+	case *types.InterfaceTypeDefinition:
+		newType := newType.(*types.InterfaceTypeDefinition)
+		d.diffInterfaces(oldType.Name, oldType.Interfaces, newType.Interfaces)
+		d.diffFields(oldType.Name, oldType.Fields, newType.Fields)
+	case *types.InputObject:
+		d.diffInputFields(oldType.Name, oldType.Values, newType.(*types.InputObject).Values)
+	case *types.EnumTypeDefinition:
+		newType := newType.(*types.EnumTypeDefinition)
+		oldValues, newValues := enumValues(oldType), enumValues(newType)
+		for _, v := range oldValues {
+			if !containsString(newValues, v) {
+				d.add(BreakingChange, oldType.Name+"."+v, "enum value removed")
+			}
+		}
+		for _, v := range newValues {
+			if !containsString(oldValues, v) {
+				d.add(DangerousChange, oldType.Name+"."+v, "enum value added, clients may not handle it")
+			}
+		}
+	case *types.Union:
+		newType := newType.(*types.Union)
+		oldMembers, newMembers := unionMembers(oldType), unionMembers(newType)
+		for _, m := range oldMembers {
+			if !containsString(newMembers, m) {
+				d.add(BreakingChange, oldType.Name, "member %s removed", m)
+			}
+		}
+		for _, m := range newMembers {
+			if !containsString(oldMembers, m) {
+				d.add(DangerousChange, oldType.Name, "member %s added, clients may not handle it", m)
+			}
+		}
+	}
+}
+
+func (d *schemaDiff) diffInterfaces(path string, oldInterfaces, newInterfaces []*types.InterfaceTypeDefinition) {
+	oldNames, newNames := interfaceNames(oldInterfaces), interfaceNames(newInterfaces)
+	for _, name := range oldNames {
+		if !containsString(newNames, name) {
+			d.add(BreakingChange, path, "no longer implements %s", name)
+		}
+	}
+	for _, name := range newNames {
+		if !containsString(oldNames, name) {
+			d.add(DangerousChange, path, "now implements %s", name)
+		}
+	}
+}
+
+func (d *schemaDiff) diffFields(typeName string, oldFields, newFields types.FieldsDefinition) {
+	for _, oldField := range oldFields {
+		path := typeName + "." + oldField.Name
+		newField := newFields.Get(oldField.Name)
+		if newField == nil {
+			d.add(BreakingChange, path, "field removed")
+			continue
+		}
+		if !isSafeOutputChange(oldField.Type, newField.Type) {
+			d.add(BreakingChange, path, "type changed from %s to %s%s",
+				oldField.Type, newField.Type, nullabilityNote(oldField.Type, newField.Type, false))
+		} else if oldField.Type.String() != newField.Type.String() {
+			d.add(SafeChange, path, "type changed from %s to %s", oldField.Type, newField.Type)
+		}
+		if oldField.Directives.Get("deprecated") == nil && newField.Directives.Get("deprecated") != nil {
+			d.add(SafeChange, path, "field deprecated")
+		}
+		d.diffArguments(path, oldField.Arguments, newField.Arguments)
+	}
+	for _, newField := range newFields {
+		if oldFields.Get(newField.Name) == nil {
+			d.add(SafeChange, typeName+"."+newField.Name, "field added")
+		}
+	}
+}
+
+func (d *schemaDiff) diffArguments(path string, oldArgs, newArgs types.ArgumentsDefinition) {
+	for _, oldArg := range oldArgs {
+		argPath := path + "(" + oldArg.Name.Name + ")"
+		newArg := newArgs.Get(oldArg.Name.Name)
+		if newArg == nil {
+			d.add(BreakingChange, argPath, "argument removed")
+			continue
+		}
+		d.diffInputValue(argPath, "argument", oldArg, newArg)
+	}
+	for _, newArg := range newArgs {
+		if oldArgs.Get(newArg.Name.Name) != nil {
+			continue
+		}
+		argPath := path + "(" + newArg.Name.Name + ")"
+		if isRequired(newArg) {
+			d.add(BreakingChange, argPath, "required argument added")
+		} else {
+			d.add(DangerousChange, argPath, "optional argument added")
+		}
+	}
+}
+
+func (d *schemaDiff) diffInputFields(typeName string, oldFields, newFields types.ArgumentsDefinition) {
+	for _, oldField := range oldFields {
+		path := typeName + "." + oldField.Name.Name
+		newField := newFields.Get(oldField.Name.Name)
+		if newField == nil {
+			d.add(BreakingChange, path, "input field removed")
+			continue
+		}
+		d.diffInputValue(path, "input field", oldField, newField)
+	}
+	for _, newField := range newFields {
+		if oldFields.Get(newField.Name.Name) != nil {
+			continue
+		}
+		path := typeName + "." + newField.Name.Name
+		if isRequired(newField) {
+			d.add(BreakingChange, path, "required input field added")
+		} else {
+			d.add(DangerousChange, path, "optional input field added")
+		}
+	}
+}
+
+func (d *schemaDiff) diffInputValue(path, kind string, oldValue, newValue *types.InputValueDefinition) {
+	if !isSafeInputChange(oldValue.Type, newValue.Type) {
+		d.add(BreakingChange, path, "%s type changed from %s to %s%s",
+			kind, oldValue.Type, newValue.Type, nullabilityNote(oldValue.Type, newValue.Type, true))
+	} else if oldValue.Type.String() != newValue.Type.String() {
+		d.add(SafeChange, path, "%s type changed from %s to %s", kind, oldValue.Type, newValue.Type)
+	}
+	oldDefault, newDefault := valueString(oldValue.Default), valueString(newValue.Default)
+	if oldDefault != newDefault {
+		d.add(DangerousChange, path, "default value changed from %s to %s", oldDefault, newDefault)
+	}
+}
+
+// isSafeOutputChange Check if clients reading a value of oldType can read
+// newType, which may only be stricter
+func isSafeOutputChange(oldType, newType types.Type) bool {
+	switch oldType := oldType.(type) {
+	case *types.NonNull:
+		newNonNull, ok := newType.(*types.NonNull)
+		return ok && isSafeOutputChange(oldType.OfType, newNonNull.OfType)
+	case *types.List:
+		switch newType := newType.(type) {
+		case *types.List:
+			return isSafeOutputChange(oldType.OfType, newType.OfType)
+		case *types.NonNull:
+			return isSafeOutputChange(oldType, newType.OfType)
+		}
+		return false
+	}
+	if newNonNull, ok := newType.(*types.NonNull); ok {
+		return isSafeOutputChange(oldType, newNonNull.OfType)
+	}
+	return oldType.String() == newType.String()
+}
+
+// isSafeInputChange Check if values clients send as oldType are still valid
+// as newType, which may only be looser
+func isSafeInputChange(oldType, newType types.Type) bool {
+	switch oldType := oldType.(type) {
+	case *types.NonNull:
+		if newNonNull, ok := newType.(*types.NonNull); ok {
+			return isSafeInputChange(oldType.OfType, newNonNull.OfType)
+		}
+		return isSafeInputChange(oldType.OfType, newType)
+	case *types.List:
+		newList, ok := newType.(*types.List)
+		return ok && isSafeInputChange(oldType.OfType, newList.OfType)
+	}
+	_, isNonNull := newType.(*types.NonNull)
+	return !isNonNull && oldType.String() == newType.String()
+}
+
+// nullabilityNote Explain a breaking change that only made the type stricter
+// for inputs or looser for outputs
+func nullabilityNote(oldType, newType types.Type, input bool) string {
+	if input && strings.ReplaceAll(newType.String(), "!", "") == strings.ReplaceAll(oldType.String(), "!", "") {
+		return " (nullability tightened)"
+	}
+	if !input && strings.ReplaceAll(newType.String(), "!", "") == strings.ReplaceAll(oldType.String(), "!", "") {
+		return " (may now be null)"
+	}
+	return ""
+}
+
+func isRequired(v *types.InputValueDefinition) bool {
+	_, nonNull := v.Type.(*types.NonNull)
+	return nonNull && v.Default == nil
+}
+
+func valueString(v types.Value) string {
+	if v == nil {
+		return "none"
+	}
+	return v.String()
+}
+
+func enumValues(t *types.EnumTypeDefinition) []string {
+	values := make([]string, len(t.EnumValuesDefinition))
+	for i, v := range t.EnumValuesDefinition {
+		values[i] = v.EnumValue
+	}
+	return values
+}
+
+func unionMembers(t *types.Union) []string {
+	members := make([]string, len(t.UnionMemberTypes))
+	for i, m := range t.UnionMemberTypes {
+		members[i] = m.Name
+	}
+	return members
+}
+
+func interfaceNames(interfaces []*types.InterfaceTypeDefinition) []string {
+	names := make([]string, len(interfaces))
+	for i, intf := range interfaces {
+		names[i] = intf.Name
+	}
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package graphqlkit
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffBaseline = `
+type Query {
+	user(id: ID!): User
+	users(first: Int): [User!]!
+	search(filter: Filter): [User!]!
+}
+type User { id: ID! name: String email: String }
+input Filter { name: String }
+enum Role { ADMIN USER }
+`
+
+func findChange(changes SchemaChanges, path string) (SchemaChange, bool) {
+	for _, change := range changes {
+		if change.Path == path {
+			return change, true
+		}
+	}
+	return SchemaChange{}, false
+}
+
+func TestDiffSchemas_ShouldClassifyTheChanges(t *testing.T) {
+	//Arrange
+	newSchema := `
+type Query {
+	user(id: ID!, active: Boolean): User
+	users(first: Int!): [User!]!
+	search(filter: Filter): [User!]!
+}
+type User { id: ID! name: String! }
+input Filter { name: String role: Role! }
+enum Role { ADMIN USER GUEST }
+`
+	expected := map[string]ChangeSeverity{
+		"User.email":         BreakingChange,
+		"Query.users(first)": BreakingChange,
+		"Filter.role":        BreakingChange,
+		"Role.GUEST":         DangerousChange,
+		"Query.user(active)": DangerousChange,
+		"User.name":          SafeChange,
+	}
+
+	//Act
+	changes, err := DiffSchemas(diffBaseline, newSchema)
+
+	//Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, severity := range expected {
+		change, ok := findChange(changes, path)
+		if !ok {
+			t.Errorf("Should have found a change in %s and returned %v\n", path, changes)
+			continue
+		}
+		if change.Severity != severity {
+			t.Errorf("Should have classified %s as %s and returned %v\n", path, severity, change)
+		}
+	}
+	if !changes.Breaking() {
+		t.Errorf("Should have been breaking and returned %v\n", changes)
+	}
+	if changes[0].Severity != BreakingChange || changes[len(changes)-1].Severity != SafeChange {
+		t.Errorf("Should have sorted by severity and returned %v\n", changes)
+	}
+	if change, _ := findChange(changes, "Query.users(first)"); !strings.Contains(change.Message, "nullability tightened") {
+		t.Errorf("Should have noted the nullability and returned %v\n", change)
+	}
+}
+
+func TestDiffSchemas_ShouldNotBreakWithTheSameSchema(t *testing.T) {
+	//Act
+	changes, err := DiffSchemas(diffBaseline, diffBaseline)
+
+	//Assert
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Should have no changes and returned %v, %v\n", changes, err)
+	}
+}
+
+func TestDiffSchemas_ShouldReturnTheSchemaError(t *testing.T) {
+	//Act
+	_, err := DiffSchemas(diffBaseline, "type Query { a: Missing }")
+
+	//Assert
+	if err == nil {
+		t.Errorf("Should have returned an error and returned %v\n", err)
+	}
+}
+
+func TestDiffBaseline_ShouldCompareTheServiceSchema(t *testing.T) {
+	//Arrange
+	h := &Handlers{}
+	if err := h.LoadGraphqlServiceFromSources(&anyResolver{}, []SchemaSource{SchemaString("schema.graphql", schema)}); err != nil {
+		t.Fatal(err)
+	}
+	baseline := schema + "\nextend type Query { removed: String }"
+
+	//Act
+	changes, err := h.DiffBaseline(baseline)
+
+	//Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change, ok := findChange(changes, "Query.removed"); !ok || change.Severity != BreakingChange {
+		t.Errorf("Should have found the removed field and returned %v\n", changes)
+	}
+}
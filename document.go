@@ -0,0 +1,665 @@
+package graphqlkit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// document A parsed graphql request. Only what graphql-kit needs to inspect a
+// request is kept, graphql-go still parses and validates it to execute.
+type document struct {
+	source     string
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	directives []*directive
+	selections []selection
+	start, end int
+}
+
+type variableDefinition struct {
+	name       string
+	typ        string
+	start, end int
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []selection
+	start, end    int
+}
+
+type selection interface {
+	span() (int, int)
+}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []selection
+	start, end int
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	start, end int
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	start, end    int
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+}
+
+type argument struct {
+	name  string
+	value *value
+}
+
+type valueKind int
+
+const (
+	variableValue valueKind = iota
+	intValue
+	floatValue
+	stringValue
+	booleanValue
+	nullValue
+	enumValue
+	listValue
+	objectValue
+)
+
+type value struct {
+	kind       valueKind
+	raw        string
+	list       []*value
+	fields     []*argument
+	start, end int
+}
+
+func (f *field) span() (int, int)          { return f.start, f.end }
+func (f *fragmentSpread) span() (int, int) { return f.start, f.end }
+func (f *inlineFragment) span() (int, int) { return f.start, f.end }
+
+// responseName Name of the field in the response
+func (f *field) responseName() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// operation Return the operation executed for operationName, nil if there is
+// none or it is ambiguous
+func (d *document) operation(operationName string) *operation {
+	if operationName == "" {
+		if len(d.operations) == 1 {
+			return d.operations[0]
+		}
+		return nil
+	}
+	for _, op := range d.operations {
+		if op.name == operationName {
+			return op
+		}
+	}
+	return nil
+}
+
+// rootFields Return the fields selected at the root of op, looking inside
+// fragments
+func (d *document) rootFields(op *operation) []*field {
+	var fields []*field
+	d.walkFields(op.selections, map[string]bool{}, func(f *field) {
+		fields = append(fields, f)
+	})
+	return fields
+}
+
+// walkFields Call fn for each field of selections, flattening fragments
+func (d *document) walkFields(selections []selection, visited map[string]bool, fn func(*field)) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			fn(s)
+		case *inlineFragment:
+			d.walkFields(s.selections, visited, fn)
+		case *fragmentSpread:
+			frag, ok := d.fragments[s.name]
+			if !ok || visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			d.walkFields(frag.selections, visited, fn)
+			delete(visited, s.name)
+		}
+	}
+}
+
+// collectVariables Add the variables referenced by selections, and by the
+// fragments they spread, to names
+func (d *document) collectVariables(selections []selection, names map[string]bool) {
+	d.collectSelectionVariables(selections, map[string]bool{}, names)
+}
+
+func (d *document) collectSelectionVariables(selections []selection, visited, names map[string]bool) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			for _, arg := range s.arguments {
+				arg.value.collectVariables(names)
+			}
+			collectDirectiveVariables(s.directives, names)
+			d.collectSelectionVariables(s.selections, visited, names)
+		case *inlineFragment:
+			collectDirectiveVariables(s.directives, names)
+			d.collectSelectionVariables(s.selections, visited, names)
+		case *fragmentSpread:
+			collectDirectiveVariables(s.directives, names)
+			frag, ok := d.fragments[s.name]
+			if !ok || visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			d.collectSelectionVariables(frag.selections, visited, names)
+		}
+	}
+}
+
+func collectDirectiveVariables(directives []*directive, names map[string]bool) {
+	for _, dir := range directives {
+		for _, arg := range dir.arguments {
+			arg.value.collectVariables(names)
+		}
+	}
+}
+
+// collectVariables Add the variables referenced by v to names
+func (v *value) collectVariables(names map[string]bool) {
+	switch v.kind {
+	case variableValue:
+		names[v.raw] = true
+	case listValue:
+		for _, item := range v.list {
+			item.collectVariables(names)
+		}
+	case objectValue:
+		for _, f := range v.fields {
+			f.value.collectVariables(names)
+		}
+	}
+}
+
+// interfaceValue Return v as decoded from json, taking variables from
+// variables
+func (v *value) interfaceValue(variables map[string]interface{}) interface{} {
+	switch v.kind {
+	case variableValue:
+		return variables[v.raw]
+	case intValue, floatValue:
+		n, _ := strconv.ParseFloat(v.raw, 64)
+		return n
+	case stringValue:
+		if strings.HasPrefix(v.raw, `"""`) {
+			return v.raw[3 : len(v.raw)-3]
+		}
+		if str, err := strconv.Unquote(v.raw); err == nil {
+			return str
+		}
+		return v.raw[1 : len(v.raw)-1]
+	case booleanValue:
+		return v.raw == "true"
+	case nullValue:
+		return nil
+	case listValue:
+		list := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			list[i] = item.interfaceValue(variables)
+		}
+		return list
+	case objectValue:
+		object := make(map[string]interface{}, len(v.fields))
+		for _, f := range v.fields {
+			object[f.name] = f.value.interfaceValue(variables)
+		}
+		return object
+	}
+	return v.raw
+}
+
+// text Return the source of the node between start and end
+func (d *document) text(start, end int) string {
+	return d.source[start:end]
+}
+
+// documentError A syntax error of a graphql request
+type documentError struct {
+	message      string
+	line, column int
+}
+
+func (e *documentError) Error() string {
+	return fmt.Sprintf("syntax error: %s (line %d, column %d)", e.message, e.line, e.column)
+}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	punctuatorToken
+	nameToken
+	intToken
+	floatToken
+	stringToken
+	blockStringToken
+)
+
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+}
+
+type documentParser struct {
+	source  string
+	pos     int
+	tok     token
+	prevEnd int
+}
+
+// parseDocument Parse a graphql request
+func parseDocument(source string) (doc *document, err error) {
+	p := &documentParser{source: source}
+	defer func() {
+		if err != nil {
+			doc = nil
+		}
+	}()
+	defer recoverDocumentError(&err)
+	p.next()
+	doc = &document{source: source, fragments: make(map[string]*fragment)}
+	for p.tok.kind != eofToken {
+		switch {
+		case p.peek("{"):
+			start := p.tok.start
+			doc.operations = append(doc.operations, &operation{
+				kind:       "query",
+				selections: p.parseSelectionSet(),
+				start:      start,
+				end:        p.prevEnd,
+			})
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			doc.operations = append(doc.operations, p.parseOperation())
+		case p.peekName("fragment"):
+			frag := p.parseFragment()
+			doc.fragments[frag.name] = frag
+		default:
+			p.fail("unexpected %q", p.tok.text)
+		}
+	}
+	return doc, nil
+}
+
+// normalizeQuery Return the tokens of query separated by a space, dropping
+// comments and insignificant whitespace
+func normalizeQuery(query string) (normalized string, err error) {
+	p := &documentParser{source: query}
+	defer recoverDocumentError(&err)
+	var sb strings.Builder
+	for p.next(); p.tok.kind != eofToken; p.next() {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(p.tok.text)
+	}
+	return sb.String(), nil
+}
+
+// recoverDocumentError Report a syntax error raised by the parser in err
+func recoverDocumentError(err *error) {
+	if r := recover(); r != nil {
+		docErr, ok := r.(*documentError)
+		if !ok {
+			panic(r)
+		}
+		*err = docErr
+	}
+}
+
+func (p *documentParser) parseOperation() *operation {
+	op := &operation{start: p.tok.start, kind: p.tok.text}
+	p.next()
+	if p.tok.kind == nameToken {
+		op.name = p.parseName()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			v := &variableDefinition{start: p.tok.start}
+			p.expect("$")
+			v.name = p.parseName()
+			p.expect(":")
+			typeStart := p.tok.start
+			p.parseType()
+			v.typ = p.source[typeStart:p.prevEnd]
+			if p.skip("=") {
+				p.parseValue(true)
+			}
+			p.parseDirectives()
+			v.end = p.prevEnd
+			op.variables = append(op.variables, v)
+		}
+	}
+	op.directives = p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	op.end = p.prevEnd
+	return op
+}
+
+func (p *documentParser) parseFragment() *fragment {
+	frag := &fragment{start: p.tok.start}
+	p.next()
+	frag.name = p.parseName()
+	if !p.skipName("on") {
+		p.fail("expected \"on\" after fragment %s", frag.name)
+	}
+	frag.typeCondition = p.parseName()
+	p.parseDirectives()
+	frag.selections = p.parseSelectionSet()
+	frag.end = p.prevEnd
+	return frag
+}
+
+func (p *documentParser) parseSelectionSet() []selection {
+	p.expect("{")
+	var selections []selection
+	for !p.skip("}") {
+		selections = append(selections, p.parseSelection())
+	}
+	return selections
+}
+
+func (p *documentParser) parseSelection() selection {
+	start := p.tok.start
+	if p.skip("...") {
+		if p.tok.kind == nameToken && p.tok.text != "on" {
+			spread := &fragmentSpread{start: start, name: p.parseName()}
+			spread.directives = p.parseDirectives()
+			spread.end = p.prevEnd
+			return spread
+		}
+		inline := &inlineFragment{start: start}
+		if p.skipName("on") {
+			inline.typeCondition = p.parseName()
+		}
+		inline.directives = p.parseDirectives()
+		inline.selections = p.parseSelectionSet()
+		inline.end = p.prevEnd
+		return inline
+	}
+	f := &field{start: start, name: p.parseName()}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.parseName()
+	}
+	f.arguments = p.parseArguments(false)
+	f.directives = p.parseDirectives()
+	if p.peek("{") {
+		f.selections = p.parseSelectionSet()
+	}
+	f.end = p.prevEnd
+	return f
+}
+
+func (p *documentParser) parseArguments(constant bool) []*argument {
+	var args []*argument
+	if !p.skip("(") {
+		return nil
+	}
+	for !p.skip(")") {
+		arg := &argument{name: p.parseName()}
+		p.expect(":")
+		arg.value = p.parseValue(constant)
+		args = append(args, arg)
+	}
+	return args
+}
+
+func (p *documentParser) parseDirectives() []*directive {
+	var directives []*directive
+	for p.skip("@") {
+		d := &directive{name: p.parseName()}
+		d.arguments = p.parseArguments(false)
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+func (p *documentParser) parseType() {
+	if p.skip("[") {
+		p.parseType()
+		p.expect("]")
+	} else {
+		p.parseName()
+	}
+	p.skip("!")
+}
+
+func (p *documentParser) parseValue(constant bool) *value {
+	v := &value{start: p.tok.start, raw: p.tok.text}
+	switch p.tok.kind {
+	case punctuatorToken:
+		switch p.tok.text {
+		case "$":
+			if constant {
+				p.fail("unexpected variable")
+			}
+			p.next()
+			v.kind = variableValue
+			v.raw = p.parseName()
+		case "[":
+			p.next()
+			v.kind = listValue
+			for !p.skip("]") {
+				v.list = append(v.list, p.parseValue(constant))
+			}
+		case "{":
+			p.next()
+			v.kind = objectValue
+			for !p.skip("}") {
+				f := &argument{name: p.parseName()}
+				p.expect(":")
+				f.value = p.parseValue(constant)
+				v.fields = append(v.fields, f)
+			}
+		default:
+			p.fail("unexpected %q", p.tok.text)
+		}
+		v.end = p.prevEnd
+		return v
+	case intToken:
+		v.kind = intValue
+	case floatToken:
+		v.kind = floatValue
+	case stringToken, blockStringToken:
+		v.kind = stringValue
+	case nameToken:
+		switch p.tok.text {
+		case "true", "false":
+			v.kind = booleanValue
+		case "null":
+			v.kind = nullValue
+		default:
+			v.kind = enumValue
+		}
+	default:
+		p.fail("unexpected end of document")
+	}
+	p.next()
+	v.end = p.prevEnd
+	return v
+}
+
+func (p *documentParser) parseName() string {
+	if p.tok.kind != nameToken {
+		p.fail("expected name, found %q", p.tok.text)
+	}
+	name := p.tok.text
+	p.next()
+	return name
+}
+
+func (p *documentParser) peek(punctuator string) bool {
+	return p.tok.kind == punctuatorToken && p.tok.text == punctuator
+}
+
+func (p *documentParser) peekName(name string) bool {
+	return p.tok.kind == nameToken && p.tok.text == name
+}
+
+func (p *documentParser) skip(punctuator string) bool {
+	if p.peek(punctuator) {
+		p.next()
+		return true
+	}
+	if p.tok.kind == eofToken {
+		p.fail("unexpected end of document, expected %q", punctuator)
+	}
+	return false
+}
+
+func (p *documentParser) skipName(name string) bool {
+	if p.peekName(name) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *documentParser) expect(punctuator string) {
+	if !p.skip(punctuator) {
+		p.fail("expected %q, found %q", punctuator, p.tok.text)
+	}
+}
+
+func (p *documentParser) fail(format string, args ...interface{}) {
+	line := 1 + strings.Count(p.source[:p.tok.start], "\n")
+	column := p.tok.start - strings.LastIndexByte(p.source[:p.tok.start], '\n')
+	panic(&documentError{fmt.Sprintf(format, args...), line, column})
+}
+
+// next Read the next token, skipping ignored tokens
+func (p *documentParser) next() {
+	p.prevEnd = p.tok.end
+	src := p.source
+	for p.pos < len(src) {
+		c := src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(src) && src[p.pos] != '\n' && src[p.pos] != '\r' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(src[p.pos:], "\ufeff") {
+			p.pos += len("\ufeff")
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	p.tok = token{start: start, end: start}
+	if p.pos >= len(src) {
+		p.tok.kind = eofToken
+		return
+	}
+	c := src[p.pos]
+	switch {
+	case strings.HasPrefix(src[p.pos:], "..."):
+		p.pos += 3
+		p.tok.kind = punctuatorToken
+	case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+		p.pos++
+		p.tok.kind = punctuatorToken
+	case c == '_' || isLetter(c):
+		for p.pos < len(src) && (src[p.pos] == '_' || isLetter(src[p.pos]) || isDigit(src[p.pos])) {
+			p.pos++
+		}
+		p.tok.kind = nameToken
+	case c == '-' || isDigit(c):
+		p.tok.kind = p.readNumber()
+	case strings.HasPrefix(src[p.pos:], `"""`):
+		end := strings.Index(strings.ReplaceAll(src[p.pos+3:], `\"""`, "XXXX"), `"""`)
+		if end < 0 {
+			p.fail("unterminated block string")
+		}
+		p.pos += end + 6
+		p.tok.kind = blockStringToken
+	case c == '"':
+		p.pos++
+		for p.pos < len(src) && src[p.pos] != '"' {
+			if src[p.pos] == '\n' || src[p.pos] == '\r' {
+				p.fail("unterminated string")
+			}
+			if src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(src) {
+			p.fail("unterminated string")
+		}
+		p.pos++
+		p.tok.kind = stringToken
+	default:
+		p.fail("unexpected character %q", c)
+	}
+	p.tok.end = p.pos
+	p.tok.text = src[start:p.pos]
+}
+
+func (p *documentParser) readNumber() tokenKind {
+	src := p.source
+	kind := intToken
+	if src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		start := p.pos
+		for p.pos < len(src) && isDigit(src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			p.fail("invalid number")
+		}
+	}
+	digits()
+	if p.pos < len(src) && src[p.pos] == '.' {
+		p.pos++
+		digits()
+		kind = floatToken
+	}
+	if p.pos < len(src) && (src[p.pos] == 'e' || src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(src) && (src[p.pos] == '+' || src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+		kind = floatToken
+	}
+	return kind
+}
@@ -0,0 +1,70 @@
+package graphqlkit
+
+import "testing"
+
+func Test_parseDocument(t *testing.T) {
+	//Arrange
+	query := `# comment
+query Op($id: ID!, $list: [String!] = ["a", "b"]) @dir(x: 1) {
+	alias: user(id: $id, filter: {name: "x\"y", n: -1.5e3, e: ENUM, b: true, z: null}) {
+		...F @include(if: true)
+		... on User { name }
+	}
+}
+fragment F on User { id, description(format: """block "quoted" text""") }`
+
+	//Act
+	doc, err := parseDocument(query)
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Should have parsed the document and returned %v\n", err)
+	}
+	op := doc.operation("")
+	if op == nil || op.kind != "query" || op.name != "Op" || len(op.variables) != 2 || op.variables[1].typ != "[String!]" {
+		t.Fatalf("Should have parsed the operation and parsed %+v\n", op)
+	}
+	user := op.selections[0].(*field)
+	if user.responseName() != "alias" || user.name != "user" || len(user.arguments) != 2 || len(user.selections) != 2 {
+		t.Errorf("Should have parsed the aliased field and parsed %+v\n", user)
+	}
+	if filter := user.arguments[1].value; filter.kind != objectValue || len(filter.fields) != 5 || filter.fields[0].value.raw != `"x\"y"` {
+		t.Errorf("Should have parsed the object argument and parsed %+v\n", filter)
+	}
+	if frag := doc.fragments["F"]; frag == nil || frag.typeCondition != "User" || len(frag.selections) != 2 {
+		t.Errorf("Should have parsed the fragment and parsed %+v\n", frag)
+	}
+	if text := doc.text(op.variables[0].start, op.variables[0].end); text != "$id: ID!" {
+		t.Errorf("Should have kept the variable position and kept %q\n", text)
+	}
+}
+
+func Test_parseDocument_WithSyntaxError_ShouldReportLine(t *testing.T) {
+	//Act
+	_, err := parseDocument("{\n  user(id: ) }")
+
+	//Assert
+	docErr, ok := err.(*documentError)
+	if !ok || docErr.line != 2 || docErr.column != 12 {
+		t.Errorf("Should have reported line 2, column 12 and reported %v\n", err)
+	}
+}
+
+func Test_document_collectVariables(t *testing.T) {
+	//Arrange
+	doc, err := parseDocument(`query($a: Int, $b: [Int], $c: Boolean, $d: Int, $unused: Int) {
+		x(p: $a, q: {r: [1, $b]}) @include(if: $c) { ...F }
+	} fragment F on T { y(p: $d) z(p: $d) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+
+	//Act
+	doc.collectVariables(doc.operations[0].selections, names)
+
+	//Assert
+	if len(names) != 4 || !names["a"] || !names["b"] || !names["c"] || !names["d"] {
+		t.Errorf("Should have collected a, b, c and d and returned %v\n", names)
+	}
+}
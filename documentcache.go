@@ -0,0 +1,210 @@
+package graphqlkit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	fields "github.com/gbaptista/requested-fields"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	httptransport "github.com/go-kit/kit/transport/http"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	documentCacheKey   contextKey = "documentCache"
+	requestDocumentKey contextKey = "requestDocument"
+)
+
+// maxFieldTrees How many requested fields trees are kept per query, one for
+// each combination of the variables of its @include and @skip directives
+const maxFieldTrees = 16
+
+// documentCache Keep the parsed queries by their hash, shared by everything
+// inspecting a request
+type documentCache struct {
+	queries *lru[*cachedQuery]
+	hits    metrics.Counter
+	misses  metrics.Counter
+}
+
+type cachedQuery struct {
+	doc *document
+	err error
+	// conditionVariables Variables of the @include and @skip directives,
+	// the only ones changing the requested fields
+	conditionVariables []string
+	mu                 sync.Mutex
+	trees              map[string]map[string][]string
+}
+
+// AddDocumentCache Keep up to size parsed queries, so identical queries of
+// different requests are parsed once by the handler, each request being
+// parsed once anyway. With instrumenting it counts the hits and misses.
+func (h *Handlers) AddDocumentCache(size int) {
+	h.documentCacheSize = size
+}
+
+func (h *Handlers) addDocumentCache() {
+	if h.documentCacheSize <= 0 {
+		return
+	}
+	cache := &documentCache{queries: newLRU[*cachedQuery](h.documentCacheSize)}
+	if h.namespace != "" {
+		cache.hits = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: h.namespace,
+			Subsystem: h.subsystem,
+			Name:      "document_cache_hits",
+			Help:      "Number of requests whose query was already parsed.",
+		}, nil)
+		cache.misses = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: h.namespace,
+			Subsystem: h.subsystem,
+			Name:      "document_cache_misses",
+			Help:      "Number of requests whose query had to be parsed.",
+		}, nil)
+	}
+	h.documentCache = cache
+	h.AddServerOptions(httptransport.ServerBefore(documentCacheToCtx(cache)))
+}
+
+func documentCacheToCtx(cache *documentCache) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return context.WithValue(ctx, documentCacheKey, cache)
+	}
+}
+
+// requestDocument The query of a request, parsed once by the first step
+// inspecting it
+type requestDocument struct {
+	query string
+	once  sync.Once
+	doc   *document
+	err   error
+}
+
+func requestDocumentToCtx(ctx context.Context, r *http.Request) context.Context {
+	params, ok := ctx.Value(GraphqlRequestKey).(GraphqlRequest)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestDocumentKey, &requestDocument{query: params.Query})
+}
+
+// documentFor Return the parsed query, parsed once per request and kept
+// across requests by the document cache of ctx if there is one
+func documentFor(ctx context.Context, query string) (*document, error) {
+	if parsed, ok := ctx.Value(requestDocumentKey).(*requestDocument); ok && parsed.query == query {
+		parsed.once.Do(func() {
+			parsed.doc, parsed.err = cachedDocument(ctx, query)
+		})
+		return parsed.doc, parsed.err
+	}
+	return cachedDocument(ctx, query)
+}
+
+// cachedDocument Return the parsed query, from the document cache of ctx if
+// there is one
+func cachedDocument(ctx context.Context, query string) (*document, error) {
+	if cache, ok := ctx.Value(documentCacheKey).(*documentCache); ok {
+		cached := cache.query(query)
+		return cached.doc, cached.err
+	}
+	return parseDocument(query)
+}
+
+// query Return the cached parse of query, parsing it on a miss
+func (c *documentCache) query(query string) *cachedQuery {
+	sum := sha256.Sum256([]byte(query))
+	key := string(sum[:])
+	if cached, ok := c.queries.get(key); ok {
+		if c.hits != nil {
+			c.hits.Add(1)
+		}
+		return cached
+	}
+	if c.misses != nil {
+		c.misses.Add(1)
+	}
+	cached := &cachedQuery{trees: make(map[string]map[string][]string)}
+	cached.doc, cached.err = parseDocument(query)
+	if cached.doc != nil {
+		cached.conditionVariables = conditionVariables(cached.doc)
+	}
+	c.queries.add(key, cached)
+	return cached
+}
+
+// fields Return the requested fields tree of query with variables
+func (c *documentCache) fields(query string, variables map[string]interface{}) map[string][]string {
+	cached := c.query(query)
+	if cached.err != nil {
+		return fields.BuildTree(query, variables)
+	}
+	values := make([]interface{}, len(cached.conditionVariables))
+	for i, name := range cached.conditionVariables {
+		values[i] = variables[name]
+	}
+	treeKey, err := json.Marshal(values)
+	if err != nil {
+		return fields.BuildTree(query, variables)
+	}
+	cached.mu.Lock()
+	tree, ok := cached.trees[string(treeKey)]
+	cached.mu.Unlock()
+	if ok {
+		return tree
+	}
+	tree = fields.BuildTree(query, variables)
+	cached.mu.Lock()
+	if len(cached.trees) < maxFieldTrees {
+		cached.trees[string(treeKey)] = tree
+	}
+	cached.mu.Unlock()
+	return tree
+}
+
+// conditionVariables Return the variables used by the @include and @skip
+// directives of doc
+func conditionVariables(doc *document) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(selections []selection)
+	addDirectives := func(directives []*directive) {
+		for _, d := range directives {
+			if d.name != "include" && d.name != "skip" {
+				continue
+			}
+			for _, arg := range d.arguments {
+				if arg.value.kind == variableValue && !seen[arg.value.raw] {
+					seen[arg.value.raw] = true
+					names = append(names, arg.value.raw)
+				}
+			}
+		}
+	}
+	walk = func(selections []selection) {
+		for _, sel := range selections {
+			switch s := sel.(type) {
+			case *field:
+				addDirectives(s.directives)
+				walk(s.selections)
+			case *inlineFragment:
+				addDirectives(s.directives)
+				walk(s.selections)
+			case *fragmentSpread:
+				addDirectives(s.directives)
+			}
+		}
+	}
+	for _, op := range doc.operations {
+		walk(op.selections)
+	}
+	for _, frag := range doc.fragments {
+		walk(frag.selections)
+	}
+	return names
+}
@@ -0,0 +1,147 @@
+package graphqlkit
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+type countingCounter struct {
+	value float64
+}
+
+func (c *countingCounter) With(labelValues ...string) metrics.Counter { return c }
+func (c *countingCounter) Add(delta float64)                          { c.value += delta }
+
+func Test_documentCache_CountsHitsAndMisses(t *testing.T) {
+	//Arrange
+	hits, misses := &countingCounter{}, &countingCounter{}
+	cache := &documentCache{queries: newLRU[*cachedQuery](2), hits: hits, misses: misses}
+	ctx := context.WithValue(context.Background(), documentCacheKey, cache)
+
+	//Act
+	first, _ := documentFor(ctx, "{ a }")
+	second, _ := documentFor(ctx, "{ a }")
+	documentFor(ctx, "{ b }")
+
+	//Assert
+	if first != second {
+		t.Errorf("Should have reused the parsed document")
+	}
+	if hits.value != 1 || misses.value != 2 {
+		t.Errorf("Should have counted 1 hit and 2 misses and returned %v and %v\n", hits.value, misses.value)
+	}
+}
+
+func Test_documentFor_ParsesTheRequestOnce(t *testing.T) {
+	//Arrange
+	ctx := context.WithValue(context.Background(), GraphqlRequestKey, GraphqlRequest{Query: "{ a }"})
+	ctx = requestDocumentToCtx(ctx, httptest.NewRequest("POST", "/graphql", nil))
+
+	//Act
+	first, _ := documentFor(ctx, "{ a }")
+	second, _ := documentFor(ctx, "{ a }")
+	other, _ := documentFor(ctx, "{ b }")
+
+	//Assert
+	if first == nil || first != second {
+		t.Errorf("Should have reused the document of the request without a cache")
+	}
+	if other == nil || other == first || len(other.operations) != 1 {
+		t.Errorf("Should have parsed other queries and returned %v\n", other)
+	}
+}
+
+func Test_documentCache_KeepsParseErrors(t *testing.T) {
+	//Arrange
+	cache := &documentCache{queries: newLRU[*cachedQuery](2)}
+	ctx := context.WithValue(context.Background(), documentCacheKey, cache)
+
+	//Act
+	documentFor(ctx, "{ a ")
+	_, err := documentFor(ctx, "{ a ")
+
+	//Assert
+	if err == nil {
+		t.Errorf("Should have returned the parse error of the cached query")
+	}
+}
+
+func Test_documentCache_FieldsByConditionVariables(t *testing.T) {
+	//Arrange
+	cache := &documentCache{queries: newLRU[*cachedQuery](2)}
+	query := `query q($withB: Boolean!, $id: ID) { a(id: $id) b @include(if: $withB) }`
+
+	//Act
+	withB := cache.fields(query, map[string]interface{}{"withB": true, "id": "1"})
+	sameB := cache.fields(query, map[string]interface{}{"withB": true, "id": "2"})
+	cache.fields(query, map[string]interface{}{"withB": false})
+
+	//Assert
+	if got := cache.query(query).conditionVariables; !reflect.DeepEqual(got, []string{"withB"}) {
+		t.Errorf("Should have found the variable of @include and returned %v\n", got)
+	}
+	if reflect.ValueOf(withB).Pointer() != reflect.ValueOf(sameB).Pointer() {
+		t.Errorf("Should have reused the fields tree of the same @include values")
+	}
+	if trees := len(cache.query(query).trees); trees != 2 {
+		t.Errorf("Should have kept a fields tree for each @include value and returned %v\n", trees)
+	}
+}
+
+func Test_operationMethod(t *testing.T) {
+	tests := []struct {
+		name string
+		req  GraphqlRequest
+		want string
+	}{
+		{"Operation name", GraphqlRequest{Query: "query q { a }", OperationName: "q"}, "q"},
+		{"First root field", GraphqlRequest{Query: "query q { ...f } fragment f on Query { b }"}, "b"},
+		{"Aliased root field", GraphqlRequest{Query: "{ x: a }"}, "a"},
+		{"Aliased root field of an invalid query", GraphqlRequest{Query: "{ login: secretField } /* x */"}, "secretField"},
+		{"Invalid query", GraphqlRequest{Query: "{  qn "}, "qn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := operationMethod(context.Background(), tt.req); got != tt.want {
+				t.Errorf("operationMethod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlers_DocumentCache(t *testing.T) {
+	//Arrange
+	setup()
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	h, err := NewHandlers(file.Name(), &queryResolver, WithDocumentCache(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := h.Handler()
+
+	//Act
+	var responses []string
+	for i := 0; i < 2; i++ {
+		req, _ := CreateGraphqlRequest(fmt.Sprintf("{ anyMethod(param: %v) }", param))
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		responses = append(responses, resp.Body.String())
+	}
+
+	//Assert
+	if responses[0] != responses[1] {
+		t.Errorf("Should have answered the same and returned %v\n", responses)
+	}
+	if h.documentCache.queries.order.Len() != 1 {
+		t.Errorf("Should have kept the parsed query")
+	}
+}
@@ -0,0 +1,461 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// EntityResolvers Resolve the entities of each type with a @key directive by
+// the representation sent by the gateway. Each one is a
+//
+//	func(ctx context.Context, representation map[string]interface{}) (T, error)
+//
+// where T resolves the type, like the resolvers of the fields returning it.
+type EntityResolvers map[string]interface{}
+
+// federationDefinitions Definitions of Apollo Federation added to the schema
+// of a subgraph, unless the schema already declares them
+var federationDefinitions = []struct{ name, definition string }{
+	{"_Any", "scalar _Any"},
+	{"_FieldSet", "scalar _FieldSet"},
+	{"key", "directive @key(fields: _FieldSet!) repeatable on OBJECT | INTERFACE"},
+	{"extends", "directive @extends on OBJECT | INTERFACE"},
+	{"external", "directive @external on OBJECT | FIELD_DEFINITION"},
+	{"requires", "directive @requires(fields: _FieldSet!) on FIELD_DEFINITION"},
+	{"provides", "directive @provides(fields: _FieldSet!) on FIELD_DEFINITION"},
+}
+
+// entityExecutors How many parsed entity schemas are kept for reuse
+const entityExecutors = 16
+
+var (
+	contextType        = reflect.TypeOf((*context.Context)(nil)).Elem()
+	representationType = reflect.TypeOf(map[string]interface{}{})
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// NewFederatedService Create a new graphql service acting as an Apollo
+// Federation subgraph. It panics if the schema can't be loaded, see
+// LoadFederatedServiceFromSources.
+func NewFederatedService(schemaFilename string, resolver interface{}, entities EntityResolvers, opts ...graphql.SchemaOpt) (Service, string) {
+	service, schemaString, err := LoadFederatedServiceFromSources(resolver, entities, []SchemaSource{SchemaFile(schemaFilename)}, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return service, schemaString
+}
+
+// LoadFederatedServiceFromSources Create a new graphql service acting as an
+// Apollo Federation subgraph, with the schema made of all documents of
+// sources. Besides the fields of the schema it answers _service { sdl } with
+// the schema as written and _entities(representations:) calling the entity
+// resolver of the type of each representation.
+func LoadFederatedServiceFromSources(
+	resolver interface{},
+	entities EntityResolvers,
+	sources []SchemaSource,
+	opts ...graphql.SchemaOpt,
+) (Service, string, error) {
+	if entities == nil {
+		entities = EntityResolvers{}
+	}
+	return loadService(&graphqlService{resolver: resolver, sources: sources, opts: opts, entities: entities})
+}
+
+// AddFederation Make the graphql service added afterwards an Apollo
+// Federation subgraph, resolving entities with entities
+func (h *Handlers) AddFederation(entities EntityResolvers) {
+	if entities == nil {
+		entities = EntityResolvers{}
+	}
+	h.entities = entities
+}
+
+// parseFederatedSchema Parse docs with the federation definitions they lack,
+// keeping _service { sdl } as the schema written in docs
+func parseFederatedSchema(
+	docs []SchemaDocument,
+	resolver interface{},
+	entities EntityResolvers,
+	opts []graphql.SchemaOpt,
+) (*graphqlSchema, error) {
+	schemaString, _ := joinSchemaDocuments(docs)
+	declared := make(map[string]bool)
+	for _, def := range scanSchemaDefinitions(schemaString) {
+		declared[def.name] = true
+	}
+	var definitions []string
+	for _, def := range federationDefinitions {
+		if !declared[def.name] {
+			definitions = append(definitions, def.definition)
+		}
+	}
+	federated := append([]SchemaDocument{{"federation.graphql", strings.Join(definitions, "\n")}}, docs...)
+	schema, err := ParseSchemaDocuments(federated, resolver, opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, locate := joinSchemaDocuments(federated)
+	ast := schema.ASTSchema()
+	ast.SchemaString = schemaString
+	es, schemaErr := newEntitySchema(ast, entities, opts)
+	if schemaErr != nil {
+		return nil, schemaErr.locate(locate)
+	}
+	return &graphqlSchema{schema: schema, schemaString: schemaString, entities: es}, nil
+}
+
+// entitySchema Resolve _entities with a schema whose query type has a list
+// field of each entity type, e0: [User]!, resolved by the fields of a struct
+// built for it holding the entities returned by the entity resolvers
+type entitySchema struct {
+	sdl   string
+	root  reflect.Type
+	types map[string]*entityType
+	opts  []graphql.SchemaOpt
+	free  chan *entityExecutor
+}
+
+type entityType struct {
+	index   int
+	object  *types.ObjectTypeDefinition
+	resolve reflect.Value
+}
+
+// entityExecutor A parsed entity schema with its own root value, used by one
+// request at a time
+type entityExecutor struct {
+	schema *graphql.Schema
+	root   reflect.Value
+}
+
+func newEntitySchema(ast *types.Schema, entities EntityResolvers, opts []graphql.SchemaOpt) (*entitySchema, *SchemaError) {
+	var names []string
+	for name, t := range ast.Types {
+		if obj, ok := t.(*types.ObjectTypeDefinition); ok && obj.Directives.Get("key") != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for name := range entities {
+		if obj, ok := ast.Types[name].(*types.ObjectTypeDefinition); !ok || obj.Directives.Get("key") == nil {
+			return nil, &SchemaError{Message: fmt.Sprintf("entity resolver of %s, which is not a type with @key", name), Type: name}
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	es := &entitySchema{
+		types: make(map[string]*entityType, len(names)),
+		opts:  opts,
+		free:  make(chan *entityExecutor, entityExecutors),
+	}
+	fields := make([]reflect.StructField, len(names))
+	var sdl strings.Builder
+	sdl.WriteString("schema {\n\tquery: _Entities\n}\n\ntype _Entities {\n")
+	for i, name := range names {
+		obj := ast.Types[name].(*types.ObjectTypeDefinition)
+		resolve := reflect.ValueOf(entities[name])
+		if !resolve.IsValid() {
+			return nil, &SchemaError{
+				Line: obj.Loc.Line, Column: obj.Loc.Column, Type: name, MissingResolver: true,
+				Message: fmt.Sprintf("%s has @key but no entity resolver", name),
+			}
+		}
+		if !isEntityResolver(resolve.Type()) {
+			return nil, &SchemaError{
+				Line: obj.Loc.Line, Column: obj.Loc.Column, Type: name,
+				Message: fmt.Sprintf("entity resolver of %s must be a func(context.Context, map[string]interface{}) (T, error)", name),
+			}
+		}
+		es.types[name] = &entityType{index: i, object: obj, resolve: resolve}
+		fields[i] = reflect.StructField{Name: "E" + strconv.Itoa(i), Type: reflect.SliceOf(resolve.Type().Out(0))}
+		fmt.Fprintf(&sdl, "\te%d: [%s]!\n", i, name)
+	}
+	sdl.WriteString("}")
+	es.root = reflect.StructOf(fields)
+	es.sdl = strings.Join(append(printDefinitions(ast), sdl.String()), "\n\n") + "\n"
+	ex, err := es.newExecutor()
+	if err != nil {
+		schemaErr := err.(*SchemaError)
+		schemaErr.Message = "resolving entities: " + schemaErr.Message
+		schemaErr.Line, schemaErr.Column = 0, 0
+		return nil, schemaErr
+	}
+	es.free <- ex
+	return es, nil
+}
+
+func isEntityResolver(t reflect.Type) bool {
+	return t.Kind() == reflect.Func &&
+		t.NumIn() == 2 && t.In(0) == contextType && t.In(1) == representationType &&
+		t.NumOut() == 2 && t.Out(1) == errorType
+}
+
+func (es *entitySchema) newExecutor() (*entityExecutor, error) {
+	root := reflect.New(es.root)
+	schema, err := ParseSchema(es.sdl, "entities.graphql", root.Interface(), es.opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &entityExecutor{schema: schema, root: root}, nil
+}
+
+func (es *entitySchema) acquire() (*entityExecutor, error) {
+	select {
+	case ex := <-es.free:
+		return ex, nil
+	default:
+		return es.newExecutor()
+	}
+}
+
+func (es *entitySchema) release(ex *entityExecutor) {
+	ex.root.Elem().Set(reflect.Zero(es.root))
+	select {
+	case es.free <- ex:
+	default:
+	}
+}
+
+// exec Resolve req if its operation selects _entities, reporting whether it
+// did. Other operations are left to the schema of the service.
+func (es *entitySchema) exec(ctx context.Context, req GraphqlRequest) (*graphql.Response, bool) {
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return nil, false
+	}
+	op := doc.operation(req.OperationName)
+	if op == nil || op.kind != "query" {
+		return nil, false
+	}
+	var entitiesField *field
+	rootFields := doc.rootFields(op)
+	for _, f := range rootFields {
+		if f.name == "_entities" {
+			entitiesField = f
+		}
+	}
+	if entitiesField == nil {
+		return nil, false
+	}
+	if len(rootFields) > 1 {
+		return entitiesError("_entities can't be selected with other fields"), true
+	}
+	var representations []interface{}
+	for _, arg := range entitiesField.arguments {
+		if arg.name == "representations" {
+			representations, _ = arg.value.interfaceValue(req.Variables).([]interface{})
+		}
+	}
+	if representations == nil {
+		return entitiesError("_entities requires a list of representations"), true
+	}
+	return es.resolve(ctx, req, doc, op, entitiesField, representations), true
+}
+
+func (es *entitySchema) resolve(
+	ctx context.Context,
+	req GraphqlRequest,
+	doc *document,
+	op *operation,
+	entitiesField *field,
+	representations []interface{},
+) *graphql.Response {
+	name := entitiesField.responseName()
+	var errs []*gqlerrors.QueryError
+	positions := make(map[int][]int)
+	values := make(map[int]reflect.Value)
+	for i, representation := range representations {
+		rep, _ := representation.(map[string]interface{})
+		typename, _ := rep["__typename"].(string)
+		t, ok := es.types[typename]
+		if !ok {
+			errs = append(errs, &gqlerrors.QueryError{
+				Message: fmt.Sprintf("unknown entity type %q", typename),
+				Path:    []interface{}{name, i},
+			})
+			continue
+		}
+		out := t.resolve.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(rep)})
+		if err, _ := out[1].Interface().(error); err != nil {
+			errs = append(errs, &gqlerrors.QueryError{
+				Message:       err.Error(),
+				Path:          []interface{}{name, i},
+				ResolverError: err,
+			})
+			continue
+		}
+		if _, ok := values[t.index]; !ok {
+			values[t.index] = reflect.MakeSlice(es.root.Field(t.index).Type, 0, 1)
+		}
+		values[t.index] = reflect.Append(values[t.index], out[0])
+		positions[t.index] = append(positions[t.index], i)
+	}
+
+	entities := make([]json.RawMessage, len(representations))
+	for i := range entities {
+		entities[i] = json.RawMessage("null")
+	}
+	if len(values) > 0 {
+		res, err := es.execEntities(ctx, req, doc, op, entitiesField, values)
+		if err != nil {
+			return entitiesError(err.Error())
+		}
+		var data map[string][]json.RawMessage
+		if len(res.Data) > 0 {
+			json.Unmarshal(res.Data, &data)
+		}
+		for key, list := range data {
+			index, _ := strconv.Atoi(strings.TrimPrefix(key, "e"))
+			for k, entity := range list {
+				entities[positions[index][k]] = entity
+			}
+		}
+		for _, err := range res.Errors {
+			if len(err.Path) >= 2 {
+				key, _ := err.Path[0].(string)
+				index, _ := strconv.Atoi(strings.TrimPrefix(key, "e"))
+				if k, ok := err.Path[1].(int); ok && k < len(positions[index]) {
+					err.Path = append([]interface{}{name, positions[index][k]}, err.Path[2:]...)
+				}
+			}
+			errs = append(errs, err)
+		}
+	}
+	data, _ := json.Marshal(map[string][]json.RawMessage{name: entities})
+	return &graphql.Response{Data: data, Errors: errs}
+}
+
+// execEntities Execute the selections of _entities for each type of values,
+// a query selecting e0 { ...on User { name } } with the values in e0
+func (es *entitySchema) execEntities(
+	ctx context.Context,
+	req GraphqlRequest,
+	doc *document,
+	op *operation,
+	entitiesField *field,
+	values map[int]reflect.Value,
+) (*graphql.Response, error) {
+	ex, err := es.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer es.release(ex)
+
+	indexes := make([]int, 0, len(values))
+	for index, value := range values {
+		ex.root.Elem().Field(index).Set(value)
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	var body strings.Builder
+	used := make(map[string]bool)
+	referenced := make(map[string]bool)
+	for _, index := range indexes {
+		var obj *types.ObjectTypeDefinition
+		for _, t := range es.types {
+			if t.index == index {
+				obj = t.object
+			}
+		}
+		selections := es.selectionsFor(doc, entitiesField.selections, obj, used, referenced)
+		if len(selections) == 0 {
+			selections = []string{"__typename"}
+		}
+		fmt.Fprintf(&body, "\te%d {\n\t\t%s\n\t}\n", index, strings.Join(selections, "\n\t\t"))
+	}
+	var fragments []string
+	for name := range used {
+		frag := doc.fragments[name]
+		fragments = append(fragments, doc.text(frag.start, frag.end))
+	}
+	sort.Strings(fragments)
+	var variables []string
+	for _, v := range op.variables {
+		if referenced[v.name] {
+			variables = append(variables, doc.text(v.start, v.end))
+		}
+	}
+	query := "query"
+	if len(variables) > 0 {
+		query += "(" + strings.Join(variables, ", ") + ")"
+	}
+	query += " {\n" + body.String() + "}\n" + strings.Join(fragments, "\n")
+	return ex.schema.Exec(ctx, query, "", req.Variables), nil
+}
+
+// selectionsFor Return the source of the selections that apply to obj,
+// adding the fragments they spread to used and the variables they reference
+// to referenced
+func (es *entitySchema) selectionsFor(
+	doc *document,
+	selections []selection,
+	obj *types.ObjectTypeDefinition,
+	used map[string]bool,
+	referenced map[string]bool,
+) []string {
+	var texts []string
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *inlineFragment:
+			if s.typeCondition != "" && !es.appliesTo(s.typeCondition, obj) {
+				continue
+			}
+		case *fragmentSpread:
+			frag, ok := doc.fragments[s.name]
+			if !ok || !es.appliesTo(frag.typeCondition, obj) {
+				continue
+			}
+		}
+		texts = append(texts, doc.text(sel.span()))
+		collectSpreads(doc, []selection{sel}, used)
+		doc.collectVariables([]selection{sel}, referenced)
+	}
+	return texts
+}
+
+// appliesTo Check if a fragment on typeCondition applies to obj
+func (es *entitySchema) appliesTo(typeCondition string, obj *types.ObjectTypeDefinition) bool {
+	if typeCondition == obj.Name || typeCondition == "_Entity" {
+		return true
+	}
+	for _, intf := range obj.Interfaces {
+		if intf.Name == typeCondition {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSpreads Add the fragments spread by selections, directly or not, to used
+func collectSpreads(doc *document, selections []selection, used map[string]bool) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			collectSpreads(doc, s.selections, used)
+		case *inlineFragment:
+			collectSpreads(doc, s.selections, used)
+		case *fragmentSpread:
+			frag, ok := doc.fragments[s.name]
+			if !ok || used[s.name] {
+				continue
+			}
+			used[s.name] = true
+			collectSpreads(doc, frag.selections, used)
+		}
+	}
+}
+
+func entitiesError(message string) *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", message)}}
+}
@@ -0,0 +1,160 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const federatedSchema = `
+type Query {
+	me: User
+}
+type User @key(fields: "id") {
+	id: ID!
+	name: String!
+}
+type Product @key(fields: "upc") {
+	upc: String!
+	price: Int!
+}`
+
+type federatedUser struct {
+	ID   graphql.ID
+	Name string
+}
+
+type federatedProduct struct {
+	UPC   string
+	Price int32
+}
+
+type federatedResolver struct{}
+
+func (r *federatedResolver) Me() *federatedUser {
+	return &federatedUser{ID: "1", Name: "Ana"}
+}
+
+var federatedEntities = EntityResolvers{
+	"User": func(ctx context.Context, rep map[string]interface{}) (*federatedUser, error) {
+		id, _ := rep["id"].(string)
+		if id == "404" {
+			return nil, errors.New("user not found")
+		}
+		return &federatedUser{ID: graphql.ID(id), Name: "user " + id}, nil
+	},
+	"Product": func(ctx context.Context, rep map[string]interface{}) (*federatedProduct, error) {
+		upc, _ := rep["upc"].(string)
+		return &federatedProduct{UPC: upc, Price: int32(len(upc))}, nil
+	},
+}
+
+func loadFederatedService(t *testing.T) Service {
+	service, _, err := LoadFederatedServiceFromSources(&federatedResolver{}, federatedEntities,
+		[]SchemaSource{SchemaString("schema.graphql", federatedSchema)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestFederatedService_ShouldResolveEntitiesInOrder(t *testing.T) {
+	//Arrange
+	service := loadFederatedService(t)
+	req := GraphqlRequest{
+		Query: `query($representations: [_Any!]!) {
+			_entities(representations: $representations) {
+				__typename
+				... on User { name }
+				...product
+			}
+		}
+		fragment product on Product { price }`,
+		Variables: map[string]interface{}{"representations": []interface{}{
+			map[string]interface{}{"__typename": "Product", "upc": "abc"},
+			map[string]interface{}{"__typename": "User", "id": "7"},
+			map[string]interface{}{"__typename": "User", "id": "404"},
+			map[string]interface{}{"__typename": "Product", "upc": "ab"},
+		}},
+	}
+
+	//Act
+	res := service.Exec(context.Background(), req)
+
+	//Assert
+	expected := `{"_entities":[{"__typename":"Product","price":3},{"__typename":"User","name":"user 7"},null,{"__typename":"Product","price":2}]}`
+	if string(res.Data) != expected {
+		t.Errorf("Should have returned %s and returned %s\n", expected, res.Data)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Message != "user not found" || res.Errors[0].Path[1] != 2 {
+		t.Errorf("Should have returned the error of the third entity and returned %v\n", res.Errors)
+	}
+}
+
+func TestFederatedService_ShouldReturnTheSchemaAsWritten(t *testing.T) {
+	//Arrange
+	service := loadFederatedService(t)
+
+	//Act
+	res := service.Exec(context.Background(), GraphqlRequest{Query: "{ _service { sdl } me { name } }"})
+
+	//Assert
+	var data struct {
+		Service struct{ SDL string } `json:"_service"`
+		Me      struct{ Name string }
+	}
+	if err := json.Unmarshal(res.Data, &data); err != nil || len(res.Errors) > 0 {
+		t.Fatalf("Should have returned the sdl and returned %s, %v\n", res.Data, res.Errors)
+	}
+	if !strings.Contains(data.Service.SDL, `@key(fields: "id")`) || strings.Contains(data.Service.SDL, "_FieldSet") {
+		t.Errorf("Should have returned the schema as written and returned %s\n", data.Service.SDL)
+	}
+	if data.Me.Name != "Ana" {
+		t.Errorf("Should have resolved the other fields and returned %s\n", res.Data)
+	}
+}
+
+func TestFederatedService_ShouldRequireAnEntityResolverPerKey(t *testing.T) {
+	//Act
+	_, _, err := LoadFederatedServiceFromSources(&federatedResolver{},
+		EntityResolvers{"User": federatedEntities["User"]},
+		[]SchemaSource{SchemaString("schema.graphql", federatedSchema)})
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Type != "Product" || schemaErr.File != "schema.graphql" || schemaErr.Line != 9 {
+		t.Errorf("Should have returned the missing entity resolver of Product and returned %v\n", err)
+	}
+}
+
+func TestFederatedService_ShouldLogEntitiesCalls(t *testing.T) {
+	//Arrange
+	var buf syncBuffer
+	h, err := NewHandlers("", &federatedResolver{},
+		WithLogger(log.NewLogfmtLogger(&buf)),
+		WithFederation(federatedEntities),
+		WithSchemaSources(SchemaString("schema.graphql", federatedSchema)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest(`{ _entities(representations: [{__typename: \"User\", id: \"3\"}]) { ... on User { name } } }`)
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), `"name":"user 3"`) {
+		t.Errorf("Should have resolved the entity and returned %s\n", resp.Body.String())
+	}
+	if !strings.Contains(buf.String(), "method=_entities") {
+		t.Errorf("Should have logged the _entities call and logged %s\n", buf.String())
+	}
+}
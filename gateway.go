@@ -0,0 +1,503 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// Backend A downstream graphql service fronted by a gateway
+type Backend struct {
+	Name string
+	URL  string
+}
+
+// gatewayIntrospectionQuery Introspection query used to fetch the schema of
+// each backend
+const gatewayIntrospectionQuery = `query IntrospectionQuery {
+	__schema {
+		queryType { name }
+		mutationType { name }
+		subscriptionType { name }
+		types {
+			kind name description
+			fields(includeDeprecated: true) {
+				name description
+				args { ...InputValue }
+				type { ...TypeRef }
+				isDeprecated deprecationReason
+			}
+			inputFields { ...InputValue }
+			interfaces { ...TypeRef }
+			enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+			possibleTypes { ...TypeRef }
+		}
+	}
+}
+fragment InputValue on __InputValue {
+	name description defaultValue
+	type { ...TypeRef }
+}
+fragment TypeRef on __Type {
+	kind name
+	ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+		ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+type introspectionSchema struct {
+	QueryType        *introspectionTypeRef `json:"queryType"`
+	MutationType     *introspectionTypeRef `json:"mutationType"`
+	SubscriptionType *introspectionTypeRef `json:"subscriptionType"`
+	Types            []*introspectionType  `json:"types"`
+}
+
+type introspectionType struct {
+	Kind          string                     `json:"kind"`
+	Name          string                     `json:"name"`
+	Description   string                     `json:"description"`
+	Fields        []*introspectionField      `json:"fields"`
+	InputFields   []*introspectionInputValue `json:"inputFields"`
+	Interfaces    []*introspectionTypeRef    `json:"interfaces"`
+	EnumValues    []*introspectionEnumValue  `json:"enumValues"`
+	PossibleTypes []*introspectionTypeRef    `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name              string                     `json:"name"`
+	Description       string                     `json:"description"`
+	Args              []*introspectionInputValue `json:"args"`
+	Type              *introspectionTypeRef      `json:"type"`
+	IsDeprecated      bool                       `json:"isDeprecated"`
+	DeprecationReason *string                    `json:"deprecationReason"`
+}
+
+type introspectionInputValue struct {
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	DefaultValue *string               `json:"defaultValue"`
+	Type         *introspectionTypeRef `json:"type"`
+}
+
+type introspectionEnumValue struct {
+	Name              string  `json:"name"`
+	Description       string  `json:"description"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+func (t *introspectionTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+type gatewayService struct {
+	client   *http.Client
+	backends []Backend
+	// owners Backend of each root field by operation kind
+	owners map[string]map[string]int
+}
+
+type gatewayResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []*gqlerrors.QueryError    `json:"errors"`
+}
+
+// gatewayPart The root fields of a request served by one backend
+type gatewayPart struct {
+	backend int
+	fields  []*field
+	res     *gatewayResponse
+	err     error
+}
+
+// DefaultGatewayIntrospectionTimeout How long AddGateway waits for the
+// introspection of all the backends
+const DefaultGatewayIntrospectionTimeout = 30 * time.Second
+
+// LoadGateway Create a Service fronting backends: their schemas, fetched by
+// introspection with client until ctx is done, are merged and each request is
+// split by root field, sending each backend the fields it serves with the
+// Authorization and X-Request-Id of the caller, and their responses merged.
+// It returns the merged schema, or an error if a backend can't be
+// introspected or two of them serve the same root field or define a type
+// differently.
+func LoadGateway(ctx context.Context, client *http.Client, backends ...Backend) (Service, string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	g := &gatewayService{
+		client:   client,
+		backends: backends,
+		owners:   map[string]map[string]int{"query": {}, "mutation": {}, "subscription": {}},
+	}
+	roots := map[string]*introspectionType{
+		"query":        {Kind: "OBJECT", Name: "Query"},
+		"mutation":     {Kind: "OBJECT", Name: "Mutation"},
+		"subscription": {Kind: "OBJECT", Name: "Subscription"},
+	}
+	types := make(map[string]*introspectionType)
+	typeOwners := make(map[string]int)
+	for i, backend := range backends {
+		schema, err := g.introspect(ctx, backend)
+		if err != nil {
+			return nil, "", err
+		}
+		rootNames := map[string]string{}
+		for kind, ref := range map[string]*introspectionTypeRef{
+			"query": schema.QueryType, "mutation": schema.MutationType, "subscription": schema.SubscriptionType,
+		} {
+			if ref != nil {
+				rootNames[ref.Name] = kind
+			}
+		}
+		for _, t := range schema.Types {
+			if strings.HasPrefix(t.Name, "__") || builtinTypes[t.Name] {
+				continue
+			}
+			if kind, ok := rootNames[t.Name]; ok {
+				for _, f := range t.Fields {
+					if owner, ok := g.owners[kind][f.Name]; ok {
+						return nil, "", fmt.Errorf("gateway: %s.%s is served by %s and %s",
+							roots[kind].Name, f.Name, backends[owner].Name, backend.Name)
+					}
+					g.owners[kind][f.Name] = i
+					roots[kind].Fields = append(roots[kind].Fields, f)
+				}
+				continue
+			}
+			if previous, ok := types[t.Name]; ok {
+				if printIntrospectionType(previous) != printIntrospectionType(t) {
+					return nil, "", fmt.Errorf("gateway: type %s is defined differently by %s and %s",
+						t.Name, backends[typeOwners[t.Name]].Name, backend.Name)
+				}
+				continue
+			}
+			types[t.Name] = t
+			typeOwners[t.Name] = i
+		}
+	}
+	for _, root := range roots {
+		if len(root.Fields) > 0 {
+			types[root.Name] = root
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	definitions := make([]string, len(names))
+	for i, name := range names {
+		definitions[i] = printIntrospectionType(types[name])
+	}
+	merged, err := ParseSchema(strings.Join(definitions, "\n\n"), "gateway.graphql", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return g, PrintSchema(merged), nil
+}
+
+// AddGateway Use a gateway fronting backends as the graphql service of the
+// handler, see LoadGateway, waiting up to DefaultGatewayIntrospectionTimeout
+// for their schemas
+func (h *Handlers) AddGateway(client *http.Client, backends ...Backend) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultGatewayIntrospectionTimeout)
+	defer cancel()
+	service, schemaString, err := LoadGateway(ctx, client, backends...)
+	if err != nil {
+		return err
+	}
+	h.service, h.schemaString = service, schemaString
+	h.graphql = service
+	return nil
+}
+
+func (g *gatewayService) introspect(ctx context.Context, backend Backend) (*introspectionSchema, error) {
+	var data struct {
+		Schema *introspectionSchema `json:"__schema"`
+	}
+	res, err := g.post(ctx, backend, GraphqlRequest{Query: gatewayIntrospectionQuery})
+	if err == nil && len(res.Errors) > 0 {
+		err = res.Errors[0]
+	}
+	if err == nil {
+		err = json.Unmarshal(res.Data["__schema"], &data.Schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gateway: introspecting %s: %w", backend.Name, err)
+	}
+	return data.Schema, nil
+}
+
+// post Send req to backend with the Authorization and X-Request-Id of ctx
+func (g *gatewayService) post(ctx context.Context, backend Backend, req GraphqlRequest) (*gatewayResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, backend.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if auth, _ := ctx.Value(httptransport.ContextKeyRequestAuthorization).(string); auth != "" {
+		httpReq.Header.Set("Authorization", auth)
+	}
+	if reqID, _ := ctx.Value(httptransport.ContextKeyRequestXRequestID).(string); reqID != "" {
+		httpReq.Header.Set("X-Request-Id", reqID)
+	}
+	resp, err := g.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var res gatewayResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("%s responded %s at %s: %w", backend.Name, resp.Status, backend.URL, err)
+	}
+	return &res, nil
+}
+
+func (g *gatewayService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return gatewayError(err.Error())
+	}
+	op := doc.operation(req.OperationName)
+	if op == nil {
+		return gatewayError("the operation to execute is not defined or ambiguous")
+	}
+	if op.kind == "subscription" {
+		return gatewayError("subscriptions are not supported by the gateway")
+	}
+	rootFields := doc.rootFields(op)
+	var parts []*gatewayPart
+	partOf := make(map[int]*gatewayPart)
+	var errs []*gqlerrors.QueryError
+	for _, f := range rootFields {
+		if f.name == "__typename" {
+			continue
+		}
+		if f.name == "__schema" || f.name == "__type" {
+			return gatewayError("introspection is not supported by the gateway")
+		}
+		backend, ok := g.owners[op.kind][f.name]
+		if !ok {
+			return gatewayError(fmt.Sprintf("cannot query field %q on %s", f.name, op.kind))
+		}
+		part, ok := partOf[backend]
+		if !ok {
+			part = &gatewayPart{backend: backend}
+			partOf[backend] = part
+			parts = append(parts, part)
+		}
+		part.fields = append(part.fields, f)
+	}
+
+	if op.kind == "mutation" {
+		for _, part := range parts {
+			g.execPart(ctx, req, doc, op, part)
+		}
+	} else {
+		var wg sync.WaitGroup
+		for _, part := range parts {
+			wg.Add(1)
+			go func(part *gatewayPart) {
+				defer wg.Done()
+				g.execPart(ctx, req, doc, op, part)
+			}(part)
+		}
+		wg.Wait()
+	}
+
+	values := make(map[string]json.RawMessage)
+	for _, part := range parts {
+		backend := g.backends[part.backend]
+		if part.err != nil {
+			for _, f := range part.fields {
+				errs = append(errs, &gqlerrors.QueryError{
+					Message:       fmt.Sprintf("%s: %v", backend.Name, part.err),
+					Path:          []interface{}{f.responseName()},
+					ResolverError: part.err,
+				})
+			}
+			continue
+		}
+		for name, value := range part.res.Data {
+			values[name] = value
+		}
+		for _, err := range part.res.Errors {
+			err.Locations = nil
+			errs = append(errs, err)
+		}
+	}
+	var data bytes.Buffer
+	data.WriteString("{")
+	written := make(map[string]bool)
+	for _, f := range rootFields {
+		name := f.responseName()
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		value, ok := values[name]
+		if f.name == "__typename" {
+			value, _ = json.Marshal(strings.ToUpper(op.kind[:1]) + op.kind[1:])
+		} else if !ok {
+			value = json.RawMessage("null")
+		}
+		if data.Len() > 1 {
+			data.WriteString(",")
+		}
+		key, _ := json.Marshal(name)
+		data.Write(key)
+		data.WriteString(":")
+		data.Write(value)
+	}
+	data.WriteString("}")
+	return &graphql.Response{Data: data.Bytes(), Errors: errs}
+}
+
+// execPart Send the root fields of part to its backend, with the variables
+// and fragments they use
+func (g *gatewayService) execPart(ctx context.Context, req GraphqlRequest, doc *document, op *operation, part *gatewayPart) {
+	texts := make([]string, len(part.fields))
+	used := make(map[string]bool)
+	referenced := make(map[string]bool)
+	for i, f := range part.fields {
+		texts[i] = doc.text(f.span())
+		collectSpreads(doc, f.selections, used)
+		doc.collectVariables([]selection{f}, referenced)
+	}
+	var fragments []string
+	for name := range used {
+		frag := doc.fragments[name]
+		fragments = append(fragments, doc.text(frag.start, frag.end))
+	}
+	sort.Strings(fragments)
+	var definitions []string
+	variables := make(map[string]interface{})
+	for _, v := range op.variables {
+		if referenced[v.name] {
+			definitions = append(definitions, doc.text(v.start, v.end))
+			if value, ok := req.Variables[v.name]; ok {
+				variables[v.name] = value
+			}
+		}
+	}
+	query := op.kind
+	if op.name != "" {
+		query += " " + op.name
+	}
+	if len(definitions) > 0 {
+		query += "(" + strings.Join(definitions, ", ") + ")"
+	}
+	query += " {\n\t" + strings.Join(texts, "\n\t") + "\n}\n" + strings.Join(fragments, "\n")
+	part.res, part.err = g.post(ctx, g.backends[part.backend], GraphqlRequest{
+		Query:         query,
+		OperationName: op.name,
+		Variables:     variables,
+	})
+}
+
+// printIntrospectionType Print the SDL of an introspected type
+func printIntrospectionType(t *introspectionType) string {
+	var sb strings.Builder
+	sb.WriteString(printDescription(t.Description, ""))
+	switch t.Kind {
+	case "SCALAR":
+		sb.WriteString("scalar " + t.Name)
+	case "OBJECT", "INTERFACE":
+		if t.Kind == "OBJECT" {
+			sb.WriteString("type " + t.Name)
+		} else {
+			sb.WriteString("interface " + t.Name)
+		}
+		if len(t.Interfaces) > 0 {
+			names := make([]string, len(t.Interfaces))
+			for i, intf := range t.Interfaces {
+				names[i] = intf.Name
+			}
+			sb.WriteString(" implements " + strings.Join(names, " & "))
+		}
+		sb.WriteString(" {\n")
+		for _, f := range t.Fields {
+			sb.WriteString(printDescription(f.Description, "\t"))
+			sb.WriteString("\t" + f.Name)
+			if len(f.Args) > 0 {
+				args := make([]string, len(f.Args))
+				for i, arg := range f.Args {
+					args[i] = printIntrospectionInputValue(arg)
+				}
+				sb.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			sb.WriteString(": " + f.Type.String() + printDeprecation(f.IsDeprecated, f.DeprecationReason) + "\n")
+		}
+		sb.WriteString("}")
+	case "UNION":
+		names := make([]string, len(t.PossibleTypes))
+		for i, member := range t.PossibleTypes {
+			names[i] = member.Name
+		}
+		sb.WriteString("union " + t.Name + " = " + strings.Join(names, " | "))
+	case "ENUM":
+		sb.WriteString("enum " + t.Name + " {\n")
+		for _, v := range t.EnumValues {
+			sb.WriteString(printDescription(v.Description, "\t"))
+			sb.WriteString("\t" + v.Name + printDeprecation(v.IsDeprecated, v.DeprecationReason) + "\n")
+		}
+		sb.WriteString("}")
+	case "INPUT_OBJECT":
+		sb.WriteString("input " + t.Name + " {\n")
+		for _, v := range t.InputFields {
+			sb.WriteString(printDescription(v.Description, "\t"))
+			sb.WriteString("\t" + printIntrospectionInputValue(v) + "\n")
+		}
+		sb.WriteString("}")
+	}
+	return sb.String()
+}
+
+func printIntrospectionInputValue(v *introspectionInputValue) string {
+	str := v.Name + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		str += " = " + *v.DefaultValue
+	}
+	return str
+}
+
+func printDeprecation(deprecated bool, reason *string) string {
+	if !deprecated {
+		return ""
+	}
+	if reason == nil {
+		return " @deprecated"
+	}
+	quoted, _ := json.Marshal(*reason)
+	return " @deprecated(reason: " + string(quoted) + ")"
+}
+
+func gatewayError(message string) *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", message)}}
+}
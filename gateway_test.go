@@ -0,0 +1,192 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type gatewayUser struct {
+	ID   graphql.ID
+	Name string
+}
+
+type usersResolver struct{}
+
+func (r *usersResolver) User(args struct{ ID graphql.ID }) *gatewayUser {
+	return &gatewayUser{ID: args.ID, Name: "user " + string(args.ID)}
+}
+
+type gatewayProduct struct {
+	UPC string
+}
+
+type productsResolver struct{}
+
+func (r *productsResolver) Products() []*gatewayProduct {
+	return []*gatewayProduct{{UPC: "a"}, {UPC: "b"}}
+}
+
+// startBackend Serve schema with resolver, recording the headers of the
+// requests received
+func startBackend(t *testing.T, schema string, resolver interface{}, headers *sync.Map) *httptest.Server {
+	h, err := NewHandlers("", resolver, WithSchemaSources(SchemaString("schema.graphql", schema)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := h.Handler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if headers != nil {
+			headers.Store("Authorization", r.Header.Get("Authorization"))
+			headers.Store("X-Request-Id", r.Header.Get("X-Request-Id"))
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func startGatewayBackends(t *testing.T, headers *sync.Map) (*httptest.Server, *httptest.Server) {
+	users := startBackend(t, `
+type Query { user(id: ID!): User }
+type User { id: ID! name: String! }`, &usersResolver{}, nil)
+	products := startBackend(t, `
+type Query { products: [Product!]! }
+type Product { upc: String! }`, &productsResolver{}, headers)
+	return users, products
+}
+
+func TestLoadGateway_ShouldMergeTheSchemas(t *testing.T) {
+	//Arrange
+	users, products := startGatewayBackends(t, nil)
+
+	//Act
+	_, schemaString, err := LoadGateway(context.Background(), nil, Backend{"users", users.URL}, Backend{"products", products.URL})
+
+	//Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"user(id: ID!): User", "products: [Product!]!", "type User", "type Product"} {
+		if !strings.Contains(schemaString, expected) {
+			t.Errorf("Should have merged %s and returned %s\n", expected, schemaString)
+		}
+	}
+}
+
+func TestLoadGateway_ShouldRejectRootFieldsServedTwice(t *testing.T) {
+	//Arrange
+	users, _ := startGatewayBackends(t, nil)
+
+	//Act
+	_, _, err := LoadGateway(context.Background(), nil, Backend{"users", users.URL}, Backend{"again", users.URL})
+
+	//Assert
+	if err == nil || !strings.Contains(err.Error(), "Query.user is served by users and again") {
+		t.Errorf("Should have rejected the conflict and returned %v\n", err)
+	}
+}
+
+func TestLoadGateway_WithHungBackend_ShouldStopWithTheContext(t *testing.T) {
+	//Arrange
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	//Act
+	_, _, err := LoadGateway(ctx, nil, Backend{"hung", hung.URL})
+
+	//Assert
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Should have given up on the hung backend and returned %v\n", err)
+	}
+}
+
+func TestLoadGateway_WithInvalidResponse_ShouldReturnTheDecodeError(t *testing.T) {
+	//Arrange
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	}))
+	defer broken.Close()
+
+	//Act
+	_, _, err := LoadGateway(context.Background(), nil, Backend{"broken", broken.URL})
+
+	//Assert
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), broken.URL) {
+		t.Errorf("Should have returned the decode error with the url and returned %v\n", err)
+	}
+}
+
+func TestGateway_ShouldSplitTheQueryAndMergeTheResponses(t *testing.T) {
+	//Arrange
+	var headers sync.Map
+	var buf syncBuffer
+	users, products := startGatewayBackends(t, &headers)
+	h, err := NewHandlers("", nil,
+		WithGateway(nil, Backend{"users", users.URL}, Backend{"products", products.URL}),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"query":"query Q($id: ID!) { a: user(id: $id) { ...u } products { upc } __typename } fragment u on User { name }","variables":{"id":"7"}}`
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Request-Id", "req-1")
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	expected := `{"data":{"a":{"name":"user 7"},"products":[{"upc":"a"},{"upc":"b"}],"__typename":"Query"}}`
+	if resp.Body.String() != expected {
+		t.Errorf("Should have returned %s and returned %s\n", expected, resp.Body.String())
+	}
+	if auth, _ := headers.Load("Authorization"); auth != "Bearer token" {
+		t.Errorf("Should have forwarded the Authorization and forwarded %v\n", auth)
+	}
+	if reqID, _ := headers.Load("X-Request-Id"); reqID != "req-1" {
+		t.Errorf("Should have forwarded the X-Request-Id and forwarded %v\n", reqID)
+	}
+	if !strings.Contains(buf.String(), "x-req-id=req-1") {
+		t.Errorf("Should have logged the request and logged %s\n", buf.String())
+	}
+}
+
+func TestGateway_ShouldReportTheFieldsOfAFailedBackend(t *testing.T) {
+	//Arrange
+	users, products := startGatewayBackends(t, nil)
+	service, _, err := LoadGateway(context.Background(), nil, Backend{"users", users.URL}, Backend{"products", products.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	products.Close()
+
+	//Act
+	res := service.Exec(context.Background(), GraphqlRequest{Query: `{ user(id: "1") { name } products { upc } }`})
+
+	//Assert
+	if string(res.Data) != `{"user":{"name":"user 1"},"products":null}` {
+		t.Errorf("Should have returned the data of the other backend and returned %s\n", res.Data)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Path[0] != "products" || !strings.HasPrefix(res.Errors[0].Message, "products: ") {
+		t.Errorf("Should have returned the error of the failed backend and returned %v\n", res.Errors)
+	}
+}
@@ -0,0 +1,70 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// GraphqlResponseMediaType The media type of the GraphQL over HTTP spec,
+// answered with status codes telling if the request could be executed
+const GraphqlResponseMediaType = "application/graphql-response+json"
+
+const graphqlResponseKey contextKey = "graphqlResponse"
+
+// serverErrorCodes Codes of the errors answered with 500, instead of 400,
+// when there is no data
+var serverErrorCodes = map[string]bool{
+	"INTERNAL":     true,
+	"AUDIT_FAILED": true,
+}
+
+// AddGraphqlResponseMediaType Answer with GraphqlResponseMediaType the
+// requests accepting it at least as much as application/json
+func (h *Handlers) AddGraphqlResponseMediaType() {
+	h.graphqlResponse = true
+}
+
+func (h *Handlers) addGraphqlResponseMediaType() {
+	if !h.graphqlResponse {
+		return
+	}
+	h.AddServerOptions(httptransport.ServerBefore(graphqlResponseToCtx))
+}
+
+func graphqlResponseToCtx(ctx context.Context, r *http.Request) context.Context {
+	if !acceptsGraphqlResponse(r.Header.Get("Accept")) {
+		return ctx
+	}
+	return context.WithValue(ctx, graphqlResponseKey, true)
+}
+
+// acceptsGraphqlResponse Tell if accept prefers GraphqlResponseMediaType, or
+// ties it, to application/json
+func acceptsGraphqlResponse(accept string) bool {
+	qualities := acceptQualities(accept)
+	quality, ok := qualities[GraphqlResponseMediaType]
+	return ok && quality > 0 && quality >= qualities["application/json"]
+}
+
+func graphqlResponseNegotiated(ctx context.Context) bool {
+	negotiated, _ := ctx.Value(graphqlResponseKey).(bool)
+	return negotiated
+}
+
+// graphqlResponseStatus The status of res in GraphqlResponseMediaType: 200
+// when there is data, even null, else 400 for request errors and 500 for
+// server ones
+func graphqlResponseStatus(res *graphql.Response) int {
+	if len(res.Data) > 0 || len(res.Errors) == 0 {
+		return http.StatusOK
+	}
+	for _, err := range res.Errors {
+		if code, _ := err.Extensions["code"].(string); serverErrorCodes[code] {
+			return http.StatusInternalServerError
+		}
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,85 @@
+package graphqlkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+func TestAcceptsGraphqlResponse(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"*/*", false},
+		{"application/graphql-response+json", true},
+		{"application/graphql-response+json, application/json;q=0.9", true},
+		{"application/graphql-response+json;q=0.5, application/json", false},
+		{"application/graphql-response+json;q=0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := acceptsGraphqlResponse(tt.accept); got != tt.want {
+				t.Errorf("Should have returned %v and returned %v\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGraphqlResponseStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *graphql.Response
+		want int
+	}{
+		{"Data", &graphql.Response{Data: json.RawMessage(`{"a":1}`)}, http.StatusOK},
+		{"Partial data", &graphql.Response{Data: json.RawMessage(`{"a":null}`), Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("failed")}}, http.StatusOK},
+		{"Null data", &graphql.Response{Data: json.RawMessage(`null`), Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("failed")}}, http.StatusOK},
+		{"Request error", &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("invalid")}}, http.StatusBadRequest},
+		{"Internal error", internalErrorResponse(), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graphqlResponseStatus(tt.res); got != tt.want {
+				t.Errorf("Should have returned %v and returned %v\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandlers_WithGraphqlResponseMediaType(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"Data", `{"query":"{ anyMethod(param: [1]) }"}`, GraphqlResponseMediaType, http.StatusOK, GraphqlResponseMediaType + "; charset=utf-8"},
+		{"Invalid query", `{"query":"{ unknown }"}`, GraphqlResponseMediaType, http.StatusBadRequest, GraphqlResponseMediaType + "; charset=utf-8"},
+		{"Invalid body", `{"query":`, GraphqlResponseMediaType, http.StatusBadRequest, GraphqlResponseMediaType + "; charset=utf-8"},
+		{"Invalid query accepting json", `{"query":"{ unknown }"}`, "application/json", http.StatusOK, "application/json; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Act
+			resp := serveCompressed(t, strings.NewReader(tt.body), map[string]string{"Accept": tt.accept},
+				WithGraphqlResponseMediaType())
+
+			//Assert
+			if resp.Code != tt.code || resp.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Should have answered %v %v and returned %v %v\n", tt.code, tt.contentType, resp.Code, resp.Header())
+			}
+			var res graphql.Response
+			if err := json.Unmarshal(resp.Body.Bytes(), &res); err != nil || (len(res.Data) == 0 && len(res.Errors) == 0) {
+				t.Errorf("Should have answered a graphql response and returned %s\n", resp.Body.String())
+			}
+		})
+	}
+}
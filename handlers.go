@@ -1,12 +1,10 @@
 package graphqlkit
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"time"
 
 	fields "github.com/gbaptista/requested-fields"
 	gokitjwt "github.com/go-kit/kit/auth/jwt"
@@ -16,6 +14,8 @@ import (
 	httptransport "github.com/go-kit/kit/transport/http"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 )
 
@@ -24,6 +24,8 @@ type contextKey string
 const (
 	SchemaKey  contextKey = "schema"
 	RequestKey contextKey = "request"
+	// GraphqlRequestKey The decoded GraphqlRequest, RequestKey has its bytes
+	GraphqlRequestKey contextKey = "graphqlRequest"
 )
 
 type authentication struct {
@@ -47,13 +49,108 @@ type Handlers struct {
 	logBlacklist          []string
 	logFullBlacklist      []string
 	logVariablesBlacklist map[string][]string
+	logRedaction          Redaction
+	logFields             []LogField
+	logLimits             LogLimits
+	logSampling           LogSampling
+	logSlowThreshold      time.Duration
 	authBlacklist         []string
 	schemaString          string
+	schemaSources         []SchemaSource
+	schemaOpts            []graphql.SchemaOpt
+	tracer                tracer.Tracer
+	reloadInterval        time.Duration
+	stopReload            context.CancelFunc
+	versions              map[string]Service
+	versionNames          []string
+	versionSelector       VersionSelector
+	defaultVersion        string
+	playground            *playground
+	introspectionRule     IntrospectionRule
+	graphql               Service
+	schemaJSON            bool
+	entities              EntityResolvers
+	responseCache         ResponseCache
+	cacheBySubject        bool
+	documentCacheSize     int
+	documentCache         *documentCache
+	loaders               map[string]LoaderFactory
+	auditSink             AuditSink
+	auditPolicy           AuditFailurePolicy
+	captureWriter         io.Writer
+	captureHeaders        []string
+	capture               *captureService
+	maxBodyBytes          int64
+	compression           *compression
+	graphqlResponse       bool
+	// versionLoads Versions of WithVersion, loaded after all options so the
+	// schema options reach them
+	versionLoads []func() error
 }
 
 // AddGraphqlService Create a new Service graphql and add to handler
-func (h *Handlers) AddGraphqlService(schema string, resolver interface{}) {
-	h.service, h.schemaString = NewService(schema, resolver)
+func (h *Handlers) AddGraphqlService(schema string, resolver interface{}, opts ...graphql.SchemaOpt) {
+	if err := h.LoadGraphqlService(schema, resolver, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// LoadGraphqlService Create a new Service graphql and add to handler,
+// returning an error instead of panicking if the schema is invalid
+func (h *Handlers) LoadGraphqlService(schema string, resolver interface{}, opts ...graphql.SchemaOpt) error {
+	return h.LoadGraphqlServiceFromSources(resolver, []SchemaSource{SchemaFile(schema)}, opts...)
+}
+
+// LoadGraphqlServiceFromSources Create a new Service graphql with the schema
+// made of all sources and add to handler
+func (h *Handlers) LoadGraphqlServiceFromSources(resolver interface{}, sources []SchemaSource, opts ...graphql.SchemaOpt) error {
+	opts, err := h.withSchemaOptions(opts)
+	if err != nil {
+		return err
+	}
+	var service Service
+	var schemaString string
+	if h.entities != nil {
+		service, schemaString, err = LoadFederatedServiceFromSources(resolver, h.entities, sources, opts...)
+	} else {
+		service, schemaString, err = LoadServiceFromSources(resolver, sources, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	h.service, h.schemaString = service, schemaString
+	h.graphql = service
+	return nil
+}
+
+// AddSchemaOptions Add graphql-go schema options used by the graphql service
+// added afterwards
+func (h *Handlers) AddSchemaOptions(opts ...graphql.SchemaOpt) {
+	h.schemaOpts = append(h.schemaOpts, opts...)
+}
+
+// AddTracer Trace queries and resolvers of the graphql service added
+// afterwards with t
+func (h *Handlers) AddTracer(t tracer.Tracer) {
+	h.tracer = t
+}
+
+// withSchemaOptions Return the schema options with opts, tracing with the
+// tracer of AddTracer, wrapped to time the resolvers when slow queries are
+// logged. A tracer of the schema options would replace it, so it is rejected.
+func (h *Handlers) withSchemaOptions(opts []graphql.SchemaOpt) ([]graphql.SchemaOpt, error) {
+	schemaOpts := append(append([]graphql.SchemaOpt{}, h.schemaOpts...), opts...)
+	t := h.tracer
+	if h.logSlowThreshold > 0 {
+		t = newTimingTracer(h.tracer)
+	}
+	if t == nil {
+		return schemaOpts, nil
+	}
+	if setsTracer(schemaOpts) {
+		return nil, ErrSchemaOptionsTracer
+	}
+	return append(schemaOpts, graphql.Tracer(t)), nil
 }
 
 // AddLoggingService Add logging Service to handler
@@ -103,6 +200,48 @@ func (h *Handlers) AddLoggingFullBlacklist(methods []string) {
 	h.logFullBlacklist = append(h.logFullBlacklist, methods...)
 }
 
+// AddLoggingRedaction Omit the paths, keys and patterns of r from the logged
+// variables and response data of every method
+func (h *Handlers) AddLoggingRedaction(r Redaction) {
+	h.logRedaction.Paths = append(h.logRedaction.Paths, r.Paths...)
+	h.logRedaction.Keys = append(h.logRedaction.Keys, r.Keys...)
+	h.logRedaction.Patterns = append(h.logRedaction.Patterns, r.Patterns...)
+}
+
+// AddLoggingFields Log fields instead of DefaultLogFields
+func (h *Handlers) AddLoggingFields(fields []LogField) {
+	h.logFields = append(h.logFields, fields...)
+}
+
+// AddLoggingLimits Truncate the logged query, variables and response
+func (h *Handlers) AddLoggingLimits(limits LogLimits) {
+	h.logLimits = limits
+}
+
+// AddLoggingSampleRate Log only rate of the successful requests, failed and
+// slow ones are always logged
+func (h *Handlers) AddLoggingSampleRate(rate float64) {
+	h.logSampling.Rate = rate
+}
+
+// AddLoggingMethodSampleRates Log only the rate of the successful requests of
+// each method, instead of the sample rate of every method
+func (h *Handlers) AddLoggingMethodSampleRates(rates map[string]float64) {
+	if h.logSampling.Methods == nil {
+		h.logSampling.Methods = make(map[string]float64)
+	}
+	for method, rate := range rates {
+		h.logSampling.Methods[method] = rate
+	}
+}
+
+// AddSlowQueryThreshold Log the requests taking longer than threshold at warn
+// level, with the whole query and how long each resolver of the graphql
+// services added afterwards took
+func (h *Handlers) AddSlowQueryThreshold(threshold time.Duration) {
+	h.logSlowThreshold = threshold
+}
+
 // AddLoggingVariablesBlacklist Add a variables list of a method for not be logging
 func (h *Handlers) AddLoggingVariablesBlacklist(methodsvariables map[string][]string) {
 	if h.logVariablesBlacklist == nil {
@@ -118,6 +257,12 @@ func (h *Handlers) AddAuthBlacklist(methods []string) {
 	h.authBlacklist = append(h.authBlacklist, methods...)
 }
 
+// AddMaxBodyBytes Answer 413 to the requests whose body is longer than n
+// bytes, DefaultMaxBodyBytes when not set
+func (h *Handlers) AddMaxBodyBytes(n int64) {
+	h.maxBodyBytes = n
+}
+
 // AddServerOptions Add server options to handler
 func (h *Handlers) AddServerOptions(options ...httptransport.ServerOption) {
 	h.options = append(h.options, options...)
@@ -125,7 +270,17 @@ func (h *Handlers) AddServerOptions(options ...httptransport.ServerOption) {
 
 // Handler Retorns the http handler with all services added
 func (h *Handlers) Handler() http.Handler {
+	if err := h.addSchemaReload(); err != nil && h.logger != nil {
+		h.logger.Log("msg", "schema reload disabled", "error", err)
+	}
+	h.addDocumentCache()
+	h.addVersions()
+	h.addResponseCache()
+	h.addIntrospectionRule()
+	h.addAuditLog()
+	schemaString := h.schemaStringFunc()
 	h.addLogging()
+	h.addCapture()
 	h.addInstrumenting()
 	var httpEndpoint endpoint.Endpoint
 	if h.authenticationEnabled() {
@@ -133,46 +288,65 @@ func (h *Handlers) Handler() http.Handler {
 	} else {
 		httpEndpoint = makeGraphqlEndpoint(h.service)
 	}
-	h.AddServerOptions(httptransport.ServerBefore(fieldsToCtx()))
-	h.AddServerOptions(httptransport.ServerBefore(schemaToCtx(h.schemaString)))
-	h.AddServerOptions(httptransport.ServerBefore(requestToCtx()))
+	maxBodyBytes := h.maxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	h.options = append([]httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(encodeError),
+		httptransport.ServerBefore(bodyToCtx(maxBodyBytes)),
+	}, h.options...)
+	h.AddServerOptions(httptransport.ServerBefore(requestDocumentToCtx))
+	h.AddServerOptions(httptransport.ServerBefore(fieldsToCtx(h.documentCache)))
+	h.AddServerOptions(httptransport.ServerBefore(schemaToCtx(schemaString)))
 	h.AddServerOptions(httptransport.ServerBefore(httptransport.PopulateRequestContext))
 	h.AddServerOptions(httptransport.ServerBefore(requestIdToCtx()))
+	h.addDataLoaders()
+	h.addCompression()
+	h.addGraphqlResponseMediaType()
 
-	return httptransport.NewServer(
-		httpEndpoint,
+	recovery := h.newPanicRecovery()
+	handler := recovery.handler(httptransport.NewServer(
+		recovery.middleware(httpEndpoint),
 		decodeGraphqlRequest,
 		encodeResponse,
 		h.options...,
-	)
+	))
+	if h.capture != nil {
+		handler = h.capture.timed(handler)
+	}
+	return handler
 }
 
-func fieldsToCtx() httptransport.RequestFunc {
+func fieldsToCtx(cache *documentCache) httptransport.RequestFunc {
 	return func(ctx context.Context, r *http.Request) context.Context {
-		var params GraphqlRequest
-		bodyBytes, _ := ioutil.ReadAll(r.Body)
-		if err := json.Unmarshal(bodyBytes, &params); err != nil {
-			fmt.Print(err)
+		params, ok := ctx.Value(GraphqlRequestKey).(GraphqlRequest)
+		if !ok {
 			return ctx
 		}
-		r.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-
+		if cache != nil {
+			return context.WithValue(ctx, fields.ContextKey, cache.fields(params.Query, params.Variables))
+		}
 		return context.WithValue(ctx,
 			fields.ContextKey, fields.BuildTree(params.Query, params.Variables))
 	}
 }
 
-func requestToCtx() httptransport.RequestFunc {
+func schemaToCtx(schemaString func(ctx context.Context) string) httptransport.RequestFunc {
 	return func(ctx context.Context, r *http.Request) context.Context {
-		bodyBytes, _ := ioutil.ReadAll(r.Body)
-		r.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
-		return context.WithValue(ctx, RequestKey, bodyBytes)
+		return context.WithValue(ctx, SchemaKey, schemaString(ctx))
 	}
 }
 
-func schemaToCtx(schemaString string) httptransport.RequestFunc {
-	return func(ctx context.Context, r *http.Request) context.Context {
-		return context.WithValue(ctx, SchemaKey, schemaString)
+// schemaStringFunc Return the current schema string of the request, following
+// reloads, versions and decorators
+func (h *Handlers) schemaStringFunc() func(ctx context.Context) string {
+	service, schemaString := h.service, h.schemaString
+	return func(ctx context.Context) string {
+		if s := graphqlServiceOf(ctx, service); s != nil {
+			return s.schemaString()
+		}
+		return schemaString
 	}
 }
 
@@ -197,26 +371,43 @@ func (h *Handlers) addLogging() {
 		h.options = append(h.options,
 			httptransport.ServerErrorLogger(h.logger),
 		)
-		h.service = NewLoggingService(h.logger, h.service, h.logBlacklist, h.logFullBlacklist, h.logVariablesBlacklist)
+		h.service = NewConfiguredLoggingService(h.logger, h.service, LoggingConfig{
+			Blacklist:          h.logBlacklist,
+			FullBlacklist:      h.logFullBlacklist,
+			VariablesBlacklist: h.logVariablesBlacklist,
+			Redaction:          h.logRedaction,
+			Fields:             h.logFields,
+			Limits:             h.logLimits,
+			Sampling:           h.logSampling,
+			SlowThreshold:      h.logSlowThreshold,
+		})
 	}
 }
 
 func (h *Handlers) addInstrumenting() {
 	if h.namespace != "" {
+		labels := fieldKeys
+		if h.versioned() {
+			labels = versionFieldKeys
+		}
 		requestCount := kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: h.namespace,
 			Subsystem: h.subsystem,
 			Name:      "request_count",
 			Help:      "Number of requests received.",
-		}, fieldKeys)
+		}, labels)
 		requestLatency := kitprometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
 			Namespace: h.namespace,
 			Subsystem: h.subsystem,
 			Name:      "request_latency_microseconds",
 			Help:      "Total duration of requests in microseconds.",
-		}, fieldKeys)
+		}, labels)
 
-		h.service = NewInstrumentingService(requestCount, requestLatency, h.service)
+		if h.versioned() {
+			h.service = NewVersionedInstrumentingService(requestCount, requestLatency, h.service)
+		} else {
+			h.service = NewInstrumentingService(requestCount, requestLatency, h.service)
+		}
 	}
 }
 
@@ -16,10 +16,13 @@ type instrumentingService struct {
 	requestCount   metrics.Counter
 	requestLatency metrics.Histogram
 	Service
+	labelVersion bool
 }
 
 var fieldKeys = []string{"method", "client", "query"}
 
+var versionFieldKeys = append(append([]string{}, fieldKeys...), "version")
+
 // NewInstrumentingService returns an instance of an instrumenting Service.
 func NewInstrumentingService(counter metrics.Counter, latency metrics.Histogram, s Service) Service {
 	return &instrumentingService{
@@ -29,6 +32,18 @@ func NewInstrumentingService(counter metrics.Counter, latency metrics.Histogram,
 	}
 }
 
+// NewVersionedInstrumentingService returns an instance of an instrumenting
+// Service that also labels the metrics with the API version, counter and
+// latency must have the "version" label.
+func NewVersionedInstrumentingService(counter metrics.Counter, latency metrics.Histogram, s Service) Service {
+	return &instrumentingService{
+		requestCount:   counter,
+		requestLatency: latency,
+		Service:        s,
+		labelVersion:   true,
+	}
+}
+
 func (s *instrumentingService) Exec(ctx context.Context, req GraphqlRequest) (res *graphql.Response) {
 	defer func(begin time.Time) {
 		standardCl, converted := ctx.Value(gokitjwt.JWTClaimsContextKey).(*jwt.StandardClaims)
@@ -42,6 +57,10 @@ func (s *instrumentingService) Exec(ctx context.Context, req GraphqlRequest) (re
 			"method", req.OperationName,
 			"client", subject,
 			"query", req.Query}
+		if s.labelVersion {
+			version, _ := ctx.Value(VersionKey).(string)
+			lvs = append(lvs, "version", version)
+		}
 		s.requestCount.With(lvs...).Add(1)
 		s.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
 	}(time.Now())
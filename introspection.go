@@ -0,0 +1,134 @@
+package graphqlkit
+
+import (
+	"context"
+	"reflect"
+
+	gokitjwt "github.com/go-kit/kit/auth/jwt"
+	jwt "github.com/golang-jwt/jwt/v4"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// ClaimsRoles Return the roles held by the caller with claims
+type ClaimsRoles func(claims jwt.Claims) []string
+
+// IntrospectionRule Decide if the request of ctx may use introspection
+type IntrospectionRule func(ctx context.Context) bool
+
+type introspectionService struct {
+	Service
+	allowed IntrospectionRule
+}
+
+// NewIntrospectionService Create a service that answers queries selecting
+// __schema or __type only when allowed returns true for the request
+func NewIntrospectionService(s Service, allowed IntrospectionRule) Service {
+	return &introspectionService{s, allowed}
+}
+
+// AuthenticatedIntrospection Allow introspection to callers authenticated by
+// the jwt token that hold at least one of roles, or to any authenticated
+// caller without roles. rolesOf may be nil to use DefaultClaimsRoles.
+func AuthenticatedIntrospection(rolesOf ClaimsRoles, roles ...string) IntrospectionRule {
+	if rolesOf == nil {
+		rolesOf = DefaultClaimsRoles
+	}
+	return func(ctx context.Context) bool {
+		claims, ok := ctx.Value(gokitjwt.JWTClaimsContextKey).(jwt.Claims)
+		if !ok {
+			return false
+		}
+		if len(roles) == 0 {
+			return true
+		}
+		for _, held := range rolesOf(claims) {
+			for _, role := range roles {
+				if held == role {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// DefaultClaimsRoles Read the roles from the "roles" or "role" key of
+// jwt.MapClaims, or from a Roles []string or Role string field of a claims struct
+func DefaultClaimsRoles(claims jwt.Claims) []string {
+	if mapClaims, ok := claims.(jwt.MapClaims); ok {
+		switch roles := mapClaims["roles"].(type) {
+		case []interface{}:
+			var names []string
+			for _, role := range roles {
+				if name, ok := role.(string); ok {
+					names = append(names, name)
+				}
+			}
+			return names
+		case string:
+			return []string{roles}
+		}
+		if role, ok := mapClaims["role"].(string); ok {
+			return []string{role}
+		}
+		return nil
+	}
+	claimsValue := reflect.Indirect(reflect.ValueOf(claims))
+	if claimsValue.Kind() != reflect.Struct {
+		return nil
+	}
+	if roles := claimsValue.FieldByName("Roles"); roles.IsValid() && roles.CanInterface() {
+		if names, ok := roles.Interface().([]string); ok {
+			return names
+		}
+	}
+	if role := claimsValue.FieldByName("Role"); role.IsValid() && role.Kind() == reflect.String {
+		return []string{role.String()}
+	}
+	return nil
+}
+
+func (s *introspectionService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	if isIntrospection(ctx, req) && !s.allowed(ctx) {
+		return &graphql.Response{Errors: []*gqlerrors.QueryError{{
+			Message:    "introspection is not allowed",
+			Extensions: map[string]interface{}{"code": "FORBIDDEN"},
+		}}}
+	}
+	return s.Service.Exec(ctx, req)
+}
+
+// isIntrospection Check if the operation of req selects __schema or __type.
+// Queries that can't be parsed here may still run in graphql-go, whose lexer
+// is more lenient, so they are treated as introspection.
+func isIntrospection(ctx context.Context, req GraphqlRequest) bool {
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return true
+	}
+	operations := doc.operations
+	if op := doc.operation(req.OperationName); op != nil {
+		operations = []*operation{op}
+	}
+	for _, op := range operations {
+		for _, f := range doc.rootFields(op) {
+			if f.name == "__schema" || f.name == "__type" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddIntrospectionRule Allow introspection queries only when allowed returns
+// true for the request, e.g. AuthenticatedIntrospection
+func (h *Handlers) AddIntrospectionRule(allowed IntrospectionRule) {
+	h.introspectionRule = allowed
+}
+
+func (h *Handlers) addIntrospectionRule() {
+	if h.introspectionRule != nil {
+		h.service = NewIntrospectionService(h.service, h.introspectionRule)
+	}
+}
@@ -0,0 +1,116 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func makeIntrospectionRequest(t *testing.T, authenticated bool, opts ...Option) *httptest.ResponseRecorder {
+	return makeIntrospectionQuery(t, "{ __schema { queryType { name } } }", authenticated, opts...)
+}
+
+func makeIntrospectionQuery(t *testing.T, query string, authenticated bool, opts ...Option) *httptest.ResponseRecorder {
+	setup()
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	opts = append(opts, WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} }))
+	if !authenticated {
+		opts = append(opts, WithAuthBlacklist("__schema"))
+	}
+	h, err := NewHandlers(file.Name(), &queryResolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest(query)
+	if authenticated {
+		req, _ = CreateGraphqlRequestWithAuthentication(query)
+	}
+	resp := httptest.NewRecorder()
+	h.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestIntrospection_WithoutToken_ShouldBeForbidden(t *testing.T) {
+	//Act
+	resp := makeIntrospectionRequest(t, false, WithIntrospectionAuth())
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "introspection is not allowed") {
+		t.Errorf("Should have forbidden the introspection and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestIntrospection_WithToken_ShouldBeAllowed(t *testing.T) {
+	//Act
+	resp := makeIntrospectionRequest(t, true, WithIntrospectionAuth())
+
+	//Assert
+	if resp.Body.String() != `{"data":{"__schema":{"queryType":{"name":"Query"}}}}` {
+		t.Errorf("Should have allowed the introspection and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestIntrospection_WithoutTokenAndUnparsableQuery_ShouldBeForbidden(t *testing.T) {
+	//Act
+	resp := makeIntrospectionQuery(t, "{ __schema { queryType { name } } } /* x */", false, WithIntrospectionAuth())
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "introspection is not allowed") {
+		t.Errorf("Should have forbidden the query it couldn't parse and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestIntrospection_WithTokenWithoutRole_ShouldBeForbidden(t *testing.T) {
+	//Act
+	resp := makeIntrospectionRequest(t, true, WithIntrospectionAuth("staff"))
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "introspection is not allowed") {
+		t.Errorf("Should have forbidden the introspection and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestDefaultClaimsRoles_WithRolesField_ShouldReturnThem(t *testing.T) {
+	//Arrange
+	claims := &struct {
+		Roles []string
+		jwt.StandardClaims
+	}{Roles: []string{"staff"}}
+
+	//Act
+	roles := DefaultClaimsRoles(claims)
+
+	//Assert
+	if len(roles) != 1 || roles[0] != "staff" {
+		t.Errorf("Should have returned the staff role and returned %v\n", roles)
+	}
+}
+
+func Test_isIntrospection(t *testing.T) {
+	tests := []struct {
+		name string
+		req  GraphqlRequest
+		want bool
+	}{
+		{"typename only", GraphqlRequest{Query: "{ __typename anyMethod(param: [1]) }"}, false},
+		{"schema inside fragment", GraphqlRequest{Query: "query Q { ...F } fragment F on Query { __schema { types { name } } }"}, true},
+		{"type inside inline fragment", GraphqlRequest{Query: `{ ... on Query { t: __type(name: "Query") { name } } }`}, true},
+		{"other operation", GraphqlRequest{Query: "query A { anyMethod(param: []) } query B { __schema { types { name } } }", OperationName: "A"}, false},
+		{"nested field named __type", GraphqlRequest{Query: "{ anyMethod(param: []) { __type } }"}, false},
+		{"unparsable query", GraphqlRequest{Query: "{ __schema { queryType { name } } } /* x */"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isIntrospection(context.Background(), tt.req); got != tt.want {
+				t.Errorf("isIntrospection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,22 +4,94 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"math/rand"
+	"net"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	gokitjwt "github.com/go-kit/kit/auth/jwt"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	httptransport "github.com/go-kit/kit/transport/http"
 	graphql "github.com/graph-gophers/graphql-go"
 )
 
+// LogField A key logged for each request
+type LogField string
+
+// Fields the logging service can log
+const (
+	LogRequestID     LogField = "x-req-id"
+	LogUser          LogField = "user"
+	LogMethod        LogField = "method"
+	LogQuery         LogField = "query"
+	LogVariables     LogField = "variables"
+	LogTook          LogField = "took"
+	LogError         LogField = "error"
+	LogResponse      LogField = "response"
+	LogVersion       LogField = "version"
+	LogClientIP      LogField = "client_ip"
+	LogUserAgent     LogField = "user_agent"
+	LogOperationType LogField = "operation_type"
+	LogResponseSize  LogField = "response_size"
+)
+
+// DefaultLogFields The fields logged unless others are configured, version
+// only for versioned APIs
+var DefaultLogFields = []LogField{
+	LogRequestID, LogUser, LogMethod, LogQuery, LogVariables, LogTook, LogError, LogResponse, LogVersion,
+}
+
+// LogLimits How many bytes of the query, variables and response are logged,
+// no limit when 0
+type LogLimits struct {
+	Query     int
+	Variables int
+	Response  int
+}
+
+// LoggingConfig What the logging service logs
+type LoggingConfig struct {
+	// Blacklist Methods logged only when they fail
+	Blacklist []string
+	// FullBlacklist Methods never logged
+	FullBlacklist []string
+	// VariablesBlacklist Variables omitted from the logs of each method
+	VariablesBlacklist map[string][]string
+	// Redaction Omitted from the logged variables and response data
+	Redaction Redaction
+	// Fields Logged for each request, DefaultLogFields when nil
+	Fields []LogField
+	Limits LogLimits
+	// Sampling Fraction of the successful requests logged
+	Sampling LogSampling
+	// SlowThreshold Requests taking longer are logged at warn level, with
+	// the whole query and how long the resolvers took, disabled when 0
+	SlowThreshold time.Duration
+}
+
+// LogSampling Fraction, greater than 0 and up to 1, of the successful
+// requests logged. Failed and slow requests are always logged.
+type LogSampling struct {
+	// Rate For every method, 1 when 0
+	Rate float64
+	// Methods Rates of some methods, instead of Rate
+	Methods map[string]float64
+}
+
 type loggingService struct {
 	logger log.Logger
 	Service
 	blacklist          map[string]bool
 	fullblacklist      map[string]bool
 	variablesblacklist map[string][]string
+	redactor           *redactor
+	fields             []LogField
+	limits             LogLimits
+	sampleRate         float64
+	methodSampleRates  map[string]float64
+	slowThreshold      time.Duration
+	random             func() float64
 }
 
 // NewLoggingService Create a logging service that logs method, query,
@@ -31,73 +103,194 @@ func NewLoggingService(
 	fullblacklist []string,
 	variablesblacklist map[string][]string,
 ) Service {
+	return NewConfiguredLoggingService(logger, s, LoggingConfig{
+		Blacklist:          blacklist,
+		FullBlacklist:      fullblacklist,
+		VariablesBlacklist: variablesblacklist,
+	})
+}
+
+// NewConfiguredLoggingService Create a logging service logging the fields of
+// config, at info level on success, warn on graphql errors and error on
+// panics
+func NewConfiguredLoggingService(logger log.Logger, s Service, config LoggingConfig) Service {
 	bl := make(map[string]bool)
-	for _, method := range blacklist {
+	for _, method := range config.Blacklist {
 		bl[strings.ToUpper(method)] = true
 	}
 	fbl := make(map[string]bool)
-	for _, method := range fullblacklist {
+	for _, method := range config.FullBlacklist {
 		fbl[strings.ToUpper(method)] = true
 	}
 	vbl := make(map[string][]string)
-	for method, variables := range variablesblacklist {
+	for method, variables := range config.VariablesBlacklist {
 		vbl[strings.ToUpper(method)] = variables
 	}
-	return &loggingService{logger, s, bl, fbl, vbl}
+	fields := config.Fields
+	if fields == nil {
+		fields = DefaultLogFields
+	}
+	sampleRate := config.Sampling.Rate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+	rates := make(map[string]float64)
+	for method, rate := range config.Sampling.Methods {
+		rates[strings.ToUpper(method)] = rate
+	}
+	return &loggingService{
+		logger, s, bl, fbl, vbl, newRedactor(config.Redaction), fields, config.Limits,
+		sampleRate, rates, config.SlowThreshold, rand.Float64,
+	}
 }
 
 func (s *loggingService) Exec(ctx context.Context, req GraphqlRequest) (res *graphql.Response) {
-	defer func(begin time.Time) {
-		var responseErr error
-		if len(res.Errors) > 0 {
-			responseErr = fmt.Errorf("request error: %v", res.Errors)
+	begin := time.Now()
+	if s.slowThreshold > 0 {
+		ctx = context.WithValue(ctx, resolverTimingsKey, newResolverTimings())
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.log(ctx, req, nil, begin, r)
+			panic(r)
 		}
-		if req.OperationName == "" {
-			req.OperationName = findOpName(req.Query)
+	}()
+	res = s.Service.Exec(ctx, req)
+	s.log(ctx, req, res, begin, nil)
+	return res
+}
+
+// log Log the request, its response and the value it panicked with, if any
+func (s *loggingService) log(ctx context.Context, req GraphqlRequest, res *graphql.Response, begin time.Time, panicked interface{}) {
+	took := time.Since(begin)
+	slow := s.slowThreshold > 0 && took >= s.slowThreshold
+	var responseErr error
+	logger := level.Info(s.logger)
+	if panicked != nil {
+		responseErr = fmt.Errorf("panic: %v", panicked)
+		logger = level.Error(s.logger)
+	} else if res != nil && len(res.Errors) > 0 {
+		responseErr = fmt.Errorf("request error: %v", res.Errors)
+		logger = level.Warn(s.logger)
+	} else if slow {
+		logger = level.Warn(s.logger)
+	}
+	method := strings.ToUpper(operationMethod(ctx, req))
+	if s.inFullBlacklist(method) {
+		return
+	}
+	if responseErr == nil && !slow && (s.inBlacklist(method) || !s.sampled(method)) {
+		return
+	}
+	var sensitive sensitiveValues
+	if schema := astSchemaOf(ctx, s.Service); schema != nil {
+		sensitive = sensitivePaths(ctx, schema, req)
+	}
+	query := sensitive.redactQuery(req.Query)
+	operationType := ""
+	if doc, err := documentFor(ctx, req.Query); err == nil {
+		if op := doc.operation(req.OperationName); op != nil {
+			operationType = op.kind
 		}
-		if s.inFullBlacklist(strings.ToUpper(req.OperationName)) {
-			return
+	}
+	operationName := operationMethod(ctx, req)
+	var responseJSON []byte
+	responseSize := 0
+	if res != nil && (s.logs(LogResponse) || s.logs(LogResponseSize)) {
+		var err error
+		responseJSON, err = json.Marshal(res)
+		if err != nil {
+			responseJSON = []byte("error marshaling response to json: " + err.Error())
 		}
-		if responseErr == nil && s.inBlacklist(strings.ToUpper(req.OperationName)) {
-			return
+		responseSize = len(responseJSON)
+		if (!s.redactor.empty() || len(sensitive.response) > 0 || sensitive.unparsed) && len(res.Data) > 0 {
+			redacted := *res
+			redacted.Data = sensitive.redactResponse(ctx, s.redactor, req, res.Data)
+			if responseJSON, err = json.Marshal(&redacted); err != nil {
+				responseJSON = []byte("error marshaling response to json: " + err.Error())
+			}
 		}
-		if req.Variables != nil && s.variablesblacklist != nil {
-			for _, variable := range s.variablesblacklist[strings.ToUpper(req.OperationName)] {
-				if _, ok := req.Variables[variable]; ok {
-					req.Variables[variable] = "(omitted)"
-				}
+	}
+	keyvals := make([]interface{}, 0, 2*len(s.fields))
+	for _, field := range s.fields {
+		var value interface{}
+		switch field {
+		case LogRequestID:
+			value, _ = ctx.Value(httptransport.ContextKeyRequestXRequestID).(string)
+		case LogUser:
+			subject, ok := claimsSubject(ctx)
+			if !ok {
+				subject = "Not Authenticated"
+			}
+			value = subject
+		case LogMethod:
+			value = operationName
+		case LogQuery:
+			value = query
+			if !slow {
+				value = truncate(query, s.limits.Query)
 			}
+		case LogVariables:
+			variablesJSON, err := json.Marshal(req.Variables)
+			if err != nil {
+				variablesJSON = []byte("error marshaling variables to json: " + err.Error())
+			} else if req.Variables != nil {
+				variablesJSON = sensitive.redactVariables(s.redactor, variablesJSON, s.variablesblacklist[method]...)
+			}
+			value = truncate(string(variablesJSON), s.limits.Variables)
+		case LogTook:
+			value = took
+		case LogError:
+			value = responseErr
+		case LogResponse:
+			value = truncate(string(responseJSON), s.limits.Response)
+		case LogVersion:
+			version, ok := ctx.Value(VersionKey).(string)
+			if !ok {
+				continue
+			}
+			value = version
+		case LogClientIP:
+			value = clientIP(ctx)
+		case LogUserAgent:
+			value, _ = ctx.Value(httptransport.ContextKeyRequestUserAgent).(string)
+		case LogOperationType:
+			value = operationType
+		case LogResponseSize:
+			value = responseSize
+		default:
+			continue
 		}
-		variablesJSON, err := json.Marshal(req.Variables)
-		if err != nil {
-			variablesJSON = []byte("error marshaling variables to json: " + err.Error())
+		keyvals = append(keyvals, string(field), value)
+	}
+	if slow {
+		keyvals = append(keyvals, "slow", true)
+		if !s.logs(LogQuery) {
+			keyvals = append(keyvals, string(LogQuery), query)
 		}
-		responseJSON, err := json.Marshal(res)
-		if err != nil {
-			responseJSON = []byte("error marshaling response to json: " + err.Error())
+		if timings, ok := ctx.Value(resolverTimingsKey).(*resolverTimings); ok {
+			keyvals = append(keyvals, "resolvers", timings.String())
 		}
-		claimsValue := reflect.ValueOf(ctx.Value(gokitjwt.JWTClaimsContextKey))
-		subject := "Not Authenticated"
-		if claimsValue.IsValid() {
-			subjectValue := claimsValue.Elem().FieldByName("Subject")
-			if subjectValue.IsValid() {
-				subject = subjectValue.String()
-			}
+	}
+	logger.Log(keyvals...)
+}
+
+func (s *loggingService) logs(field LogField) bool {
+	for _, f := range s.fields {
+		if f == field {
+			return true
 		}
-		reqID, _ := ctx.Value(httptransport.ContextKeyRequestXRequestID).(string)
-		s.logger.Log(
-			"x-req-id", reqID,
-			"user", subject,
-			"method", req.OperationName,
-			"query", req.Query,
-			"variables", string(variablesJSON),
-			"took", time.Since(begin),
-			"error", responseErr,
-			"response", string(responseJSON),
-		)
-	}(time.Now())
-	res = s.Service.Exec(ctx, req)
-	return res
+	}
+	return false
+}
+
+// sampled Decide if a successful request of method is logged
+func (s *loggingService) sampled(method string) bool {
+	rate, ok := s.methodSampleRates[method]
+	if !ok {
+		rate = s.sampleRate
+	}
+	return rate >= 1 || s.random() < rate
 }
 
 func (s *loggingService) inBlacklist(operation string) bool {
@@ -107,3 +300,28 @@ func (s *loggingService) inBlacklist(operation string) bool {
 func (s *loggingService) inFullBlacklist(operation string) bool {
 	return s.fullblacklist[operation]
 }
+
+// clientIP Return the first address of X-Forwarded-For or else the remote
+// address of the request of ctx
+func clientIP(ctx context.Context) string {
+	if forwarded, _ := ctx.Value(httptransport.ContextKeyRequestXForwardedFor).(string); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	remote, _ := ctx.Value(httptransport.ContextKeyRequestRemoteAddr).(string)
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
+
+// truncate Cut s to limit bytes, on a rune boundary, telling how much was cut
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", s[:cut], len(s)-cut)
+}
@@ -0,0 +1,291 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace/noop"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+)
+
+type stubService struct {
+	res   *graphql.Response
+	panic interface{}
+}
+
+func (s *stubService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	if s.panic != nil {
+		panic(s.panic)
+	}
+	return s.res
+}
+
+func logRequest(config LoggingConfig, stub *stubService, req GraphqlRequest) string {
+	var buf bytes.Buffer
+	s := NewConfiguredLoggingService(log.NewLogfmtLogger(&buf), stub, config)
+	ctx := context.WithValue(context.Background(), httptransport.ContextKeyRequestRemoteAddr, "10.0.0.1:4321")
+	ctx = context.WithValue(ctx, httptransport.ContextKeyRequestUserAgent, "tester")
+	func() {
+		defer func() { recover() }()
+		s.Exec(ctx, req)
+	}()
+	return buf.String()
+}
+
+func TestLoggingService_Levels(t *testing.T) {
+	tests := []struct {
+		name string
+		stub *stubService
+		want string
+	}{
+		{"Success", &stubService{res: &graphql.Response{Data: []byte(`{"a":1}`)}}, "level=info"},
+		{"GraphQL errors", &stubService{res: &graphql.Response{Errors: []*errors.QueryError{{Message: "failed"}}}}, "level=warn"},
+		{"Panic", &stubService{panic: "boom"}, `level=error`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logRequest(LoggingConfig{}, tt.stub, GraphqlRequest{Query: "{ a }"})
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Should have logged %v and returned %v\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoggingService_PanicIsLoggedAndPropagated(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	s := NewLoggingService(log.NewLogfmtLogger(&buf), &stubService{panic: "boom"}, nil, nil, nil)
+	var recovered interface{}
+
+	//Act
+	func() {
+		defer func() { recovered = recover() }()
+		s.Exec(context.Background(), GraphqlRequest{Query: "{ a }"})
+	}()
+
+	//Assert
+	if recovered != "boom" {
+		t.Errorf("Should have panicked again and returned %v\n", recovered)
+	}
+	if !strings.Contains(buf.String(), `error="panic: boom"`) {
+		t.Errorf("Should have logged the panic and returned %v\n", buf.String())
+	}
+}
+
+func TestLoggingService_Fields(t *testing.T) {
+	//Arrange
+	config := LoggingConfig{
+		Fields: []LogField{LogMethod, LogClientIP, LogUserAgent, LogOperationType, LogResponseSize},
+	}
+	stub := &stubService{res: &graphql.Response{Data: []byte(`{"a":1}`)}}
+
+	//Act
+	got := logRequest(config, stub, GraphqlRequest{Query: "mutation { a }"})
+
+	//Assert
+	want := "level=info method=a client_ip=10.0.0.1 user_agent=tester operation_type=mutation response_size=16\n"
+	if got != want {
+		t.Errorf("Should have logged only the configured fields and returned %v\n", got)
+	}
+}
+
+func TestLoggingService_Limits(t *testing.T) {
+	//Arrange
+	config := LoggingConfig{
+		Fields: []LogField{LogQuery, LogVariables, LogResponse},
+		Limits: LogLimits{Query: 5, Variables: 8, Response: 16},
+	}
+	stub := &stubService{res: &graphql.Response{Data: []byte(`{"a":"ação"}`)}}
+	req := GraphqlRequest{Query: "{ a(x: $x) }", Variables: map[string]interface{}{"x": "long value"}}
+
+	//Act
+	got := logRequest(config, stub, req)
+
+	//Assert
+	for _, want := range []string{
+		`query="{ a(x...(7 bytes truncated)"`,
+		`variables="{\"x\":\"lo...(10 bytes truncated)"`,
+		`response="{\"data\":{\"a\":\"a...(8 bytes truncated)"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Should have logged %v and returned %v\n", want, got)
+		}
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	if got := truncate("aç", 2); got != "a...(2 bytes truncated)" {
+		t.Errorf("Should have cut on a rune boundary and returned %v\n", got)
+	}
+	if got := truncate("abc", 0); got != "abc" {
+		t.Errorf("Should have kept the string without limit and returned %v\n", got)
+	}
+}
+
+type slowService struct {
+	Service
+	delay time.Duration
+}
+
+func (s *slowService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	time.Sleep(s.delay)
+	return s.Service.Exec(ctx, req)
+}
+
+func TestLoggingService_Sampling(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	ok := &stubService{res: &graphql.Response{Data: []byte(`{"a":1}`)}}
+	s := NewConfiguredLoggingService(log.NewLogfmtLogger(&buf), ok, LoggingConfig{
+		Fields:   []LogField{LogMethod},
+		Sampling: LogSampling{Rate: 0.5, Methods: map[string]float64{"b": 1}},
+	}).(*loggingService)
+	s.random = func() float64 { return 0.7 }
+
+	//Act
+	s.Exec(context.Background(), GraphqlRequest{Query: "{ a }"})
+	s.Exec(context.Background(), GraphqlRequest{Query: "{ b }"})
+	ok.res = &graphql.Response{Errors: []*errors.QueryError{{Message: "failed"}}}
+	s.Exec(context.Background(), GraphqlRequest{Query: "{ c }"})
+
+	//Assert
+	want := "level=info method=b\nlevel=warn method=c\n"
+	if buf.String() != want {
+		t.Errorf("Should have logged the method always sampled and the error and returned %v\n", buf.String())
+	}
+}
+
+func TestLoggingService_SlowQuery(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	ok := &stubService{res: &graphql.Response{Data: []byte(`{"a":1}`)}}
+	s := NewConfiguredLoggingService(log.NewLogfmtLogger(&buf), &slowService{ok, 20 * time.Millisecond}, LoggingConfig{
+		Fields:        []LogField{LogMethod},
+		Blacklist:     []string{"a"},
+		Sampling:      LogSampling{Rate: 0.000001},
+		SlowThreshold: 10 * time.Millisecond,
+	})
+
+	//Act
+	s.Exec(context.Background(), GraphqlRequest{Query: "{ a }"})
+
+	//Assert
+	want := `level=warn method=a slow=true query="{ a }" resolvers=` + "\n"
+	if buf.String() != want {
+		t.Errorf("Should have logged the slow request and returned %v\n", buf.String())
+	}
+}
+
+type timedResolver struct{}
+
+func (r *timedResolver) Slow(ctx context.Context) (string, error) {
+	time.Sleep(15 * time.Millisecond)
+	return "done", nil
+}
+
+func (r *timedResolver) Fast() string {
+	return "done"
+}
+
+func TestHandlers_SlowQueryLogsResolverTimings(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	h, err := NewHandlers("", &timedResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", `type Query { slow: String! fast: String! }`)),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+		WithLoggingSampleRate(0.000001),
+		WithSlowQueryThreshold(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest("{ slow fast }")
+
+	//Act
+	h.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	logged := buf.String()
+	if !strings.Contains(logged, "level=warn") || !strings.Contains(logged, `resolvers="Query.slow=1x`) {
+		t.Errorf("Should have logged the slow resolver and returned %v\n", logged)
+	}
+}
+
+type countingTracer struct {
+	noop.Tracer
+	queries int
+}
+
+func (t *countingTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, tracer.QueryFinishFunc) {
+	t.queries++
+	return t.Tracer.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+}
+
+func TestHandlers_SlowQueryWrapsTheTracer(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	tracer := &countingTracer{}
+	h, err := NewHandlers("", &timedResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", `type Query { slow: String! fast: String! }`)),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+		WithTracer(tracer),
+		WithSlowQueryThreshold(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest("{ slow fast }")
+
+	//Act
+	h.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	if tracer.queries != 1 || !strings.Contains(buf.String(), `resolvers="Query.slow=1x`) {
+		t.Errorf("Should have traced the query and logged the slow resolver, traced %d and logged %v\n", tracer.queries, buf.String())
+	}
+}
+
+func TestHandlers_WithoutSlowQueries_ShouldUseTheTracerOfSchemaOptions(t *testing.T) {
+	//Arrange
+	tracer := &countingTracer{}
+	h, err := NewHandlers("", &timedResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", `type Query { slow: String! fast: String! }`)),
+		WithSchemaOptions(graphql.Tracer(tracer)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest("{ fast }")
+
+	//Act
+	h.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	if tracer.queries != 1 {
+		t.Errorf("Should have traced the query and traced %d\n", tracer.queries)
+	}
+}
+
+func TestHandlers_WithSlowQueriesAndTracerOfSchemaOptions_ShouldReturnError(t *testing.T) {
+	//Act
+	_, err := NewHandlers("", &timedResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", `type Query { slow: String! fast: String! }`)),
+		WithSchemaOptions(graphql.Tracer(&countingTracer{})),
+		WithSlowQueryThreshold(10*time.Millisecond),
+	)
+
+	//Assert
+	if err != ErrSchemaOptionsTracer {
+		t.Errorf("Should have returned %v and returned %v\n", ErrSchemaOptionsTracer, err)
+	}
+}
@@ -0,0 +1,60 @@
+package graphqlkit
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru A map keeping up to size values, evicting the least recently used
+type lru[V any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type lruItem[V any] struct {
+	key   string
+	value V
+}
+
+func newLRU[V any](size int) *lru[V] {
+	return &lru[V]{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *lru[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruItem[V]).value, true
+}
+
+func (c *lru[V]) add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruItem[V]).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruItem[V]{key, value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruItem[V]).key)
+	}
+}
+
+func (c *lru[V]) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,530 @@
+package graphqlkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	gokitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	jwt "github.com/golang-jwt/jwt/v4"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqllog "github.com/graph-gophers/graphql-go/log"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+)
+
+var (
+	// ErrMissingSchema is returned by NewHandlers when no schema was informed
+	ErrMissingSchema = errors.New("graphql schema is required")
+	// ErrMissingResolver is returned by NewHandlers when no resolver was informed
+	ErrMissingResolver = errors.New("graphql resolver is required")
+	// ErrSchemaOptionsTracer is returned when the schema options set a tracer,
+	// which would replace the one of WithTracer and the slow query timings
+	ErrSchemaOptionsTracer = errors.New("graphql.Tracer can't be a schema option, use WithTracer")
+)
+
+// Option Configure a Handlers created by NewHandlers
+type Option func(*Handlers) error
+
+// NewHandlers Create a Handlers with the graphql service for schema and
+// resolver, applying and validating all options before any request is served.
+// schema may be empty if the schema is given by WithSchemaSources, or if
+// there are only versions added by WithVersion.
+func NewHandlers(schema string, resolver interface{}, opts ...Option) (*Handlers, error) {
+	h := &Handlers{}
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return nil, err
+		}
+	}
+	for _, load := range h.versionLoads {
+		if err := load(); err != nil {
+			return nil, err
+		}
+	}
+	sources := h.schemaSources
+	if schema != "" {
+		sources = append([]SchemaSource{SchemaFile(schema)}, sources...)
+	}
+	if len(sources) == 0 {
+		if (h.versioned() || h.service != nil) && resolver == nil {
+			return h, nil
+		}
+		return nil, ErrMissingSchema
+	}
+	if resolver == nil {
+		return nil, ErrMissingResolver
+	}
+	if err := h.LoadGraphqlServiceFromSources(resolver, sources); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// WithSchemaSources Add the documents of sources to the schema
+func WithSchemaSources(sources ...SchemaSource) Option {
+	return func(h *Handlers) error {
+		h.schemaSources = append(h.schemaSources, sources...)
+		return nil
+	}
+}
+
+// WithLogger Log every request with logger
+func WithLogger(logger log.Logger) Option {
+	return func(h *Handlers) error {
+		if logger == nil {
+			return errors.New("logger is nil")
+		}
+		h.AddLoggingService(logger)
+		return nil
+	}
+}
+
+// WithInstrumenting Export prometheus metrics with namespace and moduleName
+func WithInstrumenting(namespace, moduleName string) Option {
+	return func(h *Handlers) error {
+		if namespace == "" {
+			return errors.New("instrumenting namespace is required")
+		}
+		h.AddInstrumentingService(namespace, moduleName)
+		return nil
+	}
+}
+
+// WithJWT Require a jwt token signed with method, validating the key up front.
+// For asymmetric methods the key must be the PEM encoded public key.
+func WithJWT(key string, method jwt.SigningMethod, claimsFactory gokitjwt.ClaimsFactory) Option {
+	return func(h *Handlers) error {
+		if method == nil {
+			return errors.New("jwt signing method is required")
+		}
+		if key == "" {
+			return errors.New("jwt key is required")
+		}
+		if claimsFactory == nil {
+			return errors.New("jwt claims factory is required")
+		}
+		if _, err := parseVerificationKey([]byte(key), method); err != nil {
+			return fmt.Errorf("invalid jwt key for %s: %w", method.Alg(), err)
+		}
+		h.AddAuthenticationService(key, method, claimsFactory)
+		return nil
+	}
+}
+
+// WithAuthBlacklist Allow methods to be called without authentication
+func WithAuthBlacklist(methods ...string) Option {
+	return func(h *Handlers) error {
+		h.AddAuthBlacklist(methods)
+		return nil
+	}
+}
+
+// WithLoggingBlacklist Don't log methods unless they fail
+func WithLoggingBlacklist(methods ...string) Option {
+	return func(h *Handlers) error {
+		h.AddLoggingBlacklist(methods)
+		return nil
+	}
+}
+
+// WithLoggingFullBlacklist Never log methods, with or without errors
+func WithLoggingFullBlacklist(methods ...string) Option {
+	return func(h *Handlers) error {
+		h.AddLoggingFullBlacklist(methods)
+		return nil
+	}
+}
+
+// WithLoggingRedactedPaths Omit the dotted paths, e.g. input.card.number,
+// from the logged variables and response data
+func WithLoggingRedactedPaths(paths ...string) Option {
+	return func(h *Handlers) error {
+		h.AddLoggingRedaction(Redaction{Paths: paths})
+		return nil
+	}
+}
+
+// WithLoggingRedactedKeys Omit the keys, at any depth, from the logged
+// variables and response data
+func WithLoggingRedactedKeys(keys ...string) Option {
+	return func(h *Handlers) error {
+		h.AddLoggingRedaction(Redaction{Keys: keys})
+		return nil
+	}
+}
+
+// WithLoggingRedactedPatterns Omit what matches the regular expressions from
+// the logged variables and response data
+func WithLoggingRedactedPatterns(patterns ...string) Option {
+	return func(h *Handlers) error {
+		var r Redaction
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid logging redaction pattern: %w", err)
+			}
+			r.Patterns = append(r.Patterns, re)
+		}
+		h.AddLoggingRedaction(r)
+		return nil
+	}
+}
+
+// WithLoggingFields Log fields instead of DefaultLogFields
+func WithLoggingFields(fields ...LogField) Option {
+	return func(h *Handlers) error {
+		if len(fields) == 0 {
+			return errors.New("logging fields are empty")
+		}
+		h.AddLoggingFields(fields)
+		return nil
+	}
+}
+
+// WithLoggingLimits Truncate the logged query, variables and response
+func WithLoggingLimits(limits LogLimits) Option {
+	return func(h *Handlers) error {
+		if limits.Query < 0 || limits.Variables < 0 || limits.Response < 0 {
+			return errors.New("logging limits can't be negative")
+		}
+		h.AddLoggingLimits(limits)
+		return nil
+	}
+}
+
+// WithLoggingSampleRate Log only rate, greater than 0 and up to 1, of the
+// successful requests
+func WithLoggingSampleRate(rate float64) Option {
+	return func(h *Handlers) error {
+		if rate <= 0 || rate > 1 {
+			return fmt.Errorf("invalid logging sample rate %v", rate)
+		}
+		h.AddLoggingSampleRate(rate)
+		return nil
+	}
+}
+
+// WithLoggingMethodSampleRates Log only the rate of the successful requests of
+// each method
+func WithLoggingMethodSampleRates(rates map[string]float64) Option {
+	return func(h *Handlers) error {
+		for method, rate := range rates {
+			if rate <= 0 || rate > 1 {
+				return fmt.Errorf("invalid logging sample rate %v for %s", rate, method)
+			}
+		}
+		h.AddLoggingMethodSampleRates(rates)
+		return nil
+	}
+}
+
+// WithSlowQueryThreshold Log the requests taking longer than threshold at
+// warn level, with the whole query and how long each resolver took
+func WithSlowQueryThreshold(threshold time.Duration) Option {
+	return func(h *Handlers) error {
+		if threshold <= 0 {
+			return errors.New("slow query threshold must be positive")
+		}
+		h.AddSlowQueryThreshold(threshold)
+		return nil
+	}
+}
+
+// WithLoggingVariablesBlacklist Omit variables of each method from the logs
+func WithLoggingVariablesBlacklist(methodsvariables map[string][]string) Option {
+	return func(h *Handlers) error {
+		h.AddLoggingVariablesBlacklist(methodsvariables)
+		return nil
+	}
+}
+
+// WithServerOptions Add go-kit server options to the http handler
+func WithServerOptions(options ...httptransport.ServerOption) Option {
+	return func(h *Handlers) error {
+		h.AddServerOptions(options...)
+		return nil
+	}
+}
+
+// WithSchemaOptions Pass graphql-go schema options to the graphql service
+func WithSchemaOptions(opts ...graphql.SchemaOpt) Option {
+	return func(h *Handlers) error {
+		h.AddSchemaOptions(opts...)
+		return nil
+	}
+}
+
+// WithMaxDepth Reject queries nested deeper than n levels
+func WithMaxDepth(n int) Option {
+	return func(h *Handlers) error {
+		if n < 0 {
+			return fmt.Errorf("invalid max depth %d", n)
+		}
+		h.AddSchemaOptions(graphql.MaxDepth(n))
+		return nil
+	}
+}
+
+// WithMaxParallelism Limit how many resolvers of a request run in parallel
+func WithMaxParallelism(n int) Option {
+	return func(h *Handlers) error {
+		if n < 1 {
+			return fmt.Errorf("invalid max parallelism %d", n)
+		}
+		h.AddSchemaOptions(graphql.MaxParallelism(n))
+		return nil
+	}
+}
+
+// WithTracer Trace queries and resolvers with t
+func WithTracer(t tracer.Tracer) Option {
+	return func(h *Handlers) error {
+		if t == nil {
+			return errors.New("tracer is nil")
+		}
+		h.AddTracer(t)
+		return nil
+	}
+}
+
+// WithGraphqlLogger Use logger for the panics recovered by graphql-go
+func WithGraphqlLogger(logger gqllog.Logger) Option {
+	return func(h *Handlers) error {
+		if logger == nil {
+			return errors.New("graphql logger is nil")
+		}
+		h.AddSchemaOptions(graphql.Logger(logger))
+		return nil
+	}
+}
+
+// WithoutIntrospection Disable introspection queries for everyone
+func WithoutIntrospection() Option {
+	return func(h *Handlers) error {
+		h.AddSchemaOptions(graphql.DisableIntrospection())
+		return nil
+	}
+}
+
+// WithSubscribeResolverTimeout Limit how long a subscription resolver may take
+// to receive each event
+func WithSubscribeResolverTimeout(timeout time.Duration) Option {
+	return func(h *Handlers) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid subscribe resolver timeout %v", timeout)
+		}
+		h.AddSchemaOptions(graphql.SubscribeResolverTimeout(timeout))
+		return nil
+	}
+}
+
+// WithSchemaReload Development mode: reload the schema when its files change,
+// checking them every interval, see Handlers.AddSchemaReload
+func WithSchemaReload(interval time.Duration) Option {
+	return func(h *Handlers) error {
+		if interval <= 0 {
+			return fmt.Errorf("invalid schema reload interval %v", interval)
+		}
+		h.AddSchemaReload(interval)
+		return nil
+	}
+}
+
+// WithVersion Add version of the API with its own schema and resolver,
+// see Handlers.AddGraphqlVersion
+func WithVersion(version, schema string, resolver interface{}) Option {
+	return func(h *Handlers) error {
+		if resolver == nil {
+			return ErrMissingResolver
+		}
+		h.versionLoads = append(h.versionLoads, func() error {
+			return h.AddGraphqlVersion(version, schema, resolver)
+		})
+		return nil
+	}
+}
+
+// WithVersionSelector Choose how the version of each request is selected
+func WithVersionSelector(selectors ...VersionSelector) Option {
+	return func(h *Handlers) error {
+		h.SetVersionSelector(FirstVersion(selectors...))
+		return nil
+	}
+}
+
+// WithDefaultVersion Name the version used when a request doesn't select one
+func WithDefaultVersion(version string) Option {
+	return func(h *Handlers) error {
+		h.SetDefaultVersion(version)
+		return nil
+	}
+}
+
+// WithPlayground Development mode: allow Handlers.PlaygroundHandler to be
+// mounted, starting with the Authorization given by authorization
+func WithPlayground(authorization PlaygroundAuthorization) Option {
+	return func(h *Handlers) error {
+		h.AddPlayground(authorization)
+		return nil
+	}
+}
+
+// WithIntrospectionAuth Allow introspection only to authenticated callers
+// holding one of roles, or to any authenticated caller without roles
+func WithIntrospectionAuth(roles ...string) Option {
+	return func(h *Handlers) error {
+		h.AddIntrospectionRule(AuthenticatedIntrospection(nil, roles...))
+		return nil
+	}
+}
+
+// WithIntrospectionRule Allow introspection only when allowed returns true
+func WithIntrospectionRule(allowed IntrospectionRule) Option {
+	return func(h *Handlers) error {
+		if allowed == nil {
+			return errors.New("introspection rule is nil")
+		}
+		h.AddIntrospectionRule(allowed)
+		return nil
+	}
+}
+
+// WithSchemaJSON Allow Handlers.SchemaHandler to answer with the
+// introspection result as json
+func WithSchemaJSON() Option {
+	return func(h *Handlers) error {
+		h.AddSchemaJSON()
+		return nil
+	}
+}
+
+// WithFederation Make the service an Apollo Federation subgraph, resolving
+// the entities of each type with @key by entities
+func WithFederation(entities EntityResolvers) Option {
+	return func(h *Handlers) error {
+		h.AddFederation(entities)
+		return nil
+	}
+}
+
+// WithGateway Front backends with a gateway instead of resolving a schema,
+// NewHandlers is then called without schema and resolver
+func WithGateway(client *http.Client, backends ...Backend) Option {
+	return func(h *Handlers) error {
+		return h.AddGateway(client, backends...)
+	}
+}
+
+// WithResponseCache Cache the responses of queries with @cacheControl hints
+// in cache, by the jwt subject too when bySubject is true
+func WithResponseCache(cache ResponseCache, bySubject bool) Option {
+	return func(h *Handlers) error {
+		if cache == nil {
+			return errors.New("response cache is nil")
+		}
+		h.AddResponseCache(cache, bySubject)
+		return nil
+	}
+}
+
+// WithDocumentCache Keep up to size parsed queries, shared by the steps
+// inspecting the requests
+func WithDocumentCache(size int) Option {
+	return func(h *Handlers) error {
+		if size <= 0 {
+			return errors.New("document cache size must be positive")
+		}
+		h.AddDocumentCache(size)
+		return nil
+	}
+}
+
+// WithDataLoader Create a loader with factory for each request, found by
+// the resolvers with dataloader.For(ctx, name)
+func WithDataLoader(name string, factory LoaderFactory) Option {
+	return func(h *Handlers) error {
+		if factory == nil {
+			return errors.New("data loader factory is nil")
+		}
+		h.AddDataLoader(name, factory)
+		return nil
+	}
+}
+
+// WithAuditLog Record every mutation in sink, apart from the logs
+func WithAuditLog(sink AuditSink) Option {
+	return func(h *Handlers) error {
+		if sink == nil {
+			return errors.New("audit sink is nil")
+		}
+		h.AddAuditLog(sink)
+		return nil
+	}
+}
+
+// WithAuditFailurePolicy Choose what happens to the response of a mutation
+// whose record couldn't be stored
+func WithAuditFailurePolicy(policy AuditFailurePolicy) Option {
+	return func(h *Handlers) error {
+		if policy != AuditFailClosed && policy != AuditFailOpen {
+			return fmt.Errorf("invalid audit failure policy %d", policy)
+		}
+		h.AddAuditFailurePolicy(policy)
+		return nil
+	}
+}
+
+// WithCapture Write every request, with the headers listed, and its response
+// to w as json lines, to be replayed with Replay
+func WithCapture(w io.Writer, headers ...string) Option {
+	return func(h *Handlers) error {
+		if w == nil {
+			return errors.New("capture writer is nil")
+		}
+		h.AddCapture(w, headers)
+		return nil
+	}
+}
+
+// WithMaxBodyBytes Answer 413 to the requests whose body is longer than n
+// bytes
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handlers) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid max body bytes %d", n)
+		}
+		h.AddMaxBodyBytes(n)
+		return nil
+	}
+}
+
+// WithCompression Compress the responses of at least minSize bytes with the
+// encoding preferred by Accept-Encoding, among compressors, gzip and deflate
+func WithCompression(minSize int, compressors ...Compressor) Option {
+	return func(h *Handlers) error {
+		if minSize < 0 {
+			return fmt.Errorf("invalid compression min size %d", minSize)
+		}
+		for _, compressor := range compressors {
+			if compressor == nil || compressor.Encoding() == "" {
+				return errors.New("compressor without encoding")
+			}
+		}
+		h.AddCompression(minSize, compressors...)
+		return nil
+	}
+}
+
+// WithGraphqlResponseMediaType Answer with GraphqlResponseMediaType, and its
+// status codes, the requests accepting it
+func WithGraphqlResponseMediaType() Option {
+	return func(h *Handlers) error {
+		h.AddGraphqlResponseMediaType()
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func TestNewHandlers_WithoutSchema_ShouldReturnError(t *testing.T) {
+	//Act
+	_, err := NewHandlers("", &queryResolver)
+
+	//Assert
+	if err != ErrMissingSchema {
+		t.Errorf("Should have returned %v and returned %v\n", ErrMissingSchema, err)
+	}
+}
+
+func TestNewHandlers_WithInvalidRSAKey_ShouldReturnError(t *testing.T) {
+	//Arrange
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+
+	//Act
+	_, err = NewHandlers(file.Name(), &queryResolver,
+		WithJWT("not a pem key", jwt.SigningMethodRS256, func() jwt.Claims { return &customClaims{} }))
+
+	//Assert
+	if err == nil {
+		t.Error("Should have returned an error for an invalid key, but it didn't.\n")
+	}
+}
+
+func TestNewHandlers_WithAllOptions_ShouldAuthenticateAndLog(t *testing.T) {
+	//Arrange
+	setup()
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	var buf bytes.Buffer
+	h, err := NewHandlers(file.Name(), &queryResolver,
+		WithLogger(log.NewLogfmtLogger(&buf)),
+		WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} }),
+		WithAuthBlacklist("anyMethod2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequestWithAuthentication(fmt.Sprintf("{ anyMethod(param: %v) }", param))
+	resp := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", h.Handler())
+
+	//Act
+	mux.ServeHTTP(resp, req)
+
+	//Assert
+	CheckResponseOk(resp, t)
+	if buf.Len() == 0 {
+		t.Error("Should have logged, but it didn't.\n")
+	}
+}
+
+func TestNewHandlers_WithMaxDepth_ShouldRejectDeeperQueries(t *testing.T) {
+	//Arrange
+	setup()
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	h, err := NewHandlers(file.Name(), &queryResolver, WithMaxDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest("{ __schema { types { name } } }")
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "exceeds max depth") {
+		t.Errorf("Should have rejected the query by its depth and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestNewHandlers_WithAuthBlacklist_ShouldNotMatchAliases(t *testing.T) {
+	//Arrange
+	setup()
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	h, err := NewHandlers(file.Name(), &queryResolver,
+		WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} }),
+		WithAuthBlacklist("anyMethod2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := CreateGraphqlRequest(fmt.Sprintf("{ anyMethod2: anyMethod(param: %v) }", param))
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("Should have required authentication for the aliased field and returned %v %s\n", resp.Code, resp.Body.String())
+	}
+}
@@ -0,0 +1,88 @@
+package graphqlkit
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+)
+
+// ErrPlaygroundDisabled is returned by PlaygroundHandler when the playground
+// wasn't enabled with AddPlayground
+var ErrPlaygroundDisabled = errors.New("playground is disabled, it must be enabled with AddPlayground")
+
+//go:embed playground.html
+var playgroundHTML string
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(playgroundHTML))
+
+// PlaygroundAuthorization Return the Authorization header the playground
+// starts with for r, or "" to start without it
+type PlaygroundAuthorization func(r *http.Request) string
+
+// AuthorizationFromCookie Start the playground with a bearer token read from
+// the cookie name
+func AuthorizationFromCookie(name string) PlaygroundAuthorization {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return ""
+		}
+		return "Bearer " + cookie.Value
+	}
+}
+
+// AuthorizationFromHeader Start the playground with the Authorization header
+// of the request that opened it
+func AuthorizationFromHeader() PlaygroundAuthorization {
+	return func(r *http.Request) string {
+		return r.Header.Get("Authorization")
+	}
+}
+
+// StaticAuthorization Always start the playground with authorization
+func StaticAuthorization(authorization string) PlaygroundAuthorization {
+	return func(*http.Request) string {
+		return authorization
+	}
+}
+
+type playground struct {
+	authorization PlaygroundAuthorization
+}
+
+// AddPlayground Development mode: allow PlaygroundHandler to be mounted.
+// authorization may be nil to start the playground without credentials.
+func (h *Handlers) AddPlayground(authorization PlaygroundAuthorization) {
+	h.playground = &playground{authorization}
+}
+
+// PlaygroundHandler Return a handler serving a self-contained playground, a
+// small query editor with a docs panel rather than GraphiQL, that sends its
+// requests to endpointPath. It fails unless the playground was enabled by
+// AddPlayground.
+func (h *Handlers) PlaygroundHandler(endpointPath string) (http.Handler, error) {
+	if h.playground == nil {
+		return nil, ErrPlaygroundDisabled
+	}
+	authorization := h.playground.authorization
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := map[string]string{}
+		if authorization != nil {
+			if value := authorization(r); value != "" {
+				headers["Authorization"] = value
+			}
+		}
+		headersJSON, _ := json.MarshalIndent(headers, "", "  ")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		err := playgroundTemplate.Execute(w, struct {
+			Endpoint string
+			Headers  string
+		}{endpointPath, string(headersJSON)})
+		if err != nil && h.logger != nil {
+			h.logger.Log("msg", "playground", "error", err)
+		}
+	}), nil
+}
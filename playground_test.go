@@ -0,0 +1,47 @@
+package graphqlkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPlaygroundHandler_WithoutPlayground_ShouldReturnError(t *testing.T) {
+	//Arrange
+	var h Handlers
+
+	//Act
+	_, err := h.PlaygroundHandler("/graphql")
+
+	//Assert
+	if err != ErrPlaygroundDisabled {
+		t.Errorf("Should have returned %v and returned %v\n", ErrPlaygroundDisabled, err)
+	}
+}
+
+func TestPlaygroundHandler_WithAuthorizationCookie_ShouldFillTheHeader(t *testing.T) {
+	//Arrange
+	var h Handlers
+	h.AddPlayground(AuthorizationFromCookie("token"))
+	handler, err := h.PlaygroundHandler("/graphql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/playground", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: "abc"})
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	CheckResponseOk(resp, t)
+	body := resp.Body.String()
+	if !strings.Contains(body, `&#34;Authorization&#34;: &#34;Bearer abc&#34;`) {
+		t.Errorf("Should have filled the Authorization header and returned %s\n", body)
+	}
+	if !strings.Contains(body, `var endpoint = "/graphql";`) {
+		t.Error("Should have pointed the playground to the endpoint, but it didn't.\n")
+	}
+}
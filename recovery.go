@@ -0,0 +1,95 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// panicRecovery Turn the panics of a request into an INTERNAL error, logging
+// them with their stack and counting them
+type panicRecovery struct {
+	logger log.Logger
+	panics metrics.Counter
+}
+
+func (h *Handlers) newPanicRecovery() *panicRecovery {
+	p := &panicRecovery{logger: h.logger}
+	if p.logger == nil {
+		p.logger = log.NewNopLogger()
+	}
+	if h.namespace != "" {
+		p.panics = kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: h.namespace,
+			Subsystem: h.subsystem,
+			Name:      "panics_total",
+			Help:      "Number of requests that panicked.",
+		}, nil)
+	}
+	return p
+}
+
+// internalErrorResponse What clients get instead of the panic
+func internalErrorResponse() *graphql.Response {
+	return &graphql.Response{Errors: []*gqlerrors.QueryError{{
+		Message:    "internal error",
+		Extensions: map[string]interface{}{"code": "INTERNAL"},
+	}}}
+}
+
+// middleware Recover the panics of the endpoint, answering an INTERNAL error
+func (p *panicRecovery) middleware(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				subject, ok := claimsSubject(ctx)
+				if !ok {
+					subject = "Not Authenticated"
+				}
+				reqID, _ := ctx.Value(httptransport.ContextKeyRequestXRequestID).(string)
+				p.recovered(r, "x-req-id", reqID, "user", subject)
+				response, err = internalErrorResponse(), nil
+			}
+		}()
+		return next(ctx, request)
+	}
+}
+
+// handler Recover the panics of next outside the endpoint, e.g. in the
+// ServerBefore functions or while encoding
+func (p *panicRecovery) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				p.recovered(rec, "x-req-id", r.Header.Get("X-Request-Id"))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(internalErrorResponse())
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *panicRecovery) recovered(r interface{}, keyvals ...interface{}) {
+	if p.panics != nil {
+		p.panics.Add(1)
+	}
+	keyvals = append(keyvals, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	level.Error(p.logger).Log(keyvals...)
+}
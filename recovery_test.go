@@ -0,0 +1,73 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func servePanicking(t *testing.T, opts ...httptransport.ServerOption) (*httptest.ResponseRecorder, string) {
+	var buf bytes.Buffer
+	h := &Handlers{service: &stubService{panic: "boom"}}
+	h.AddLoggingService(log.NewLogfmtLogger(&buf))
+	h.AddServerOptions(opts...)
+	req, _ := CreateGraphqlRequest("{ a }")
+	req.Header.Set("X-Request-Id", "req-1")
+	resp := httptest.NewRecorder()
+	h.Handler().ServeHTTP(resp, req)
+	return resp, buf.String()
+}
+
+func TestHandlers_PanicInService_ShouldAnswerInternalError(t *testing.T) {
+	//Act
+	resp, logged := servePanicking(t)
+
+	//Assert
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"code":"INTERNAL"`) {
+		t.Errorf("Should have answered an INTERNAL error and returned %v %v\n", resp.Code, resp.Body.String())
+	}
+	for _, want := range []string{"level=error", "x-req-id=req-1", `user="Not Authenticated"`, "panic=boom", "recovery.go"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Should have logged %v and returned %v\n", want, logged)
+		}
+	}
+}
+
+func TestHandlers_PanicBeforeEndpoint_ShouldAnswerInternalError(t *testing.T) {
+	//Act
+	resp, logged := servePanicking(t, httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+		panic("before")
+	}))
+
+	//Assert
+	if resp.Code != http.StatusInternalServerError || !strings.Contains(resp.Body.String(), `"code":"INTERNAL"`) {
+		t.Errorf("Should have answered an INTERNAL error and returned %v %v\n", resp.Code, resp.Body.String())
+	}
+	if !strings.Contains(logged, "panic=before") || !strings.Contains(logged, "x-req-id=req-1") {
+		t.Errorf("Should have logged the panic and returned %v\n", logged)
+	}
+}
+
+func TestPanicRecovery_CountsPanics(t *testing.T) {
+	//Arrange
+	counter := &countingCounter{}
+	p := &panicRecovery{logger: log.NewNopLogger(), panics: counter}
+	end := p.middleware(func(ctx context.Context, request interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	//Act
+	end(context.Background(), GraphqlRequest{})
+	end(context.Background(), GraphqlRequest{})
+
+	//Assert
+	if counter.value != 2 {
+		t.Errorf("Should have counted 2 panics and returned %v\n", counter.value)
+	}
+}
@@ -0,0 +1,153 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// omitted What redacted values are logged as
+const omitted = "(omitted)"
+
+// Redaction What is omitted from the logged variables and response data
+type Redaction struct {
+	// Paths Dotted paths from the root of the variables or of the response
+	// data, e.g. input.card.number, lists are crossed without an index
+	Paths []string
+	// Keys Omitted at any depth, ignoring case, e.g. password
+	Keys []string
+	// Patterns The parts of string values matching them are omitted, e.g.
+	// emails or card numbers
+	Patterns []*regexp.Regexp
+}
+
+type redactor struct {
+	paths    map[string]bool
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(r Redaction) *redactor {
+	red := &redactor{
+		paths:    make(map[string]bool),
+		keys:     make(map[string]bool),
+		patterns: r.Patterns,
+	}
+	for _, path := range r.Paths {
+		red.paths[path] = true
+	}
+	for _, key := range r.Keys {
+		red.keys[strings.ToLower(key)] = true
+	}
+	return red
+}
+
+func (r *redactor) empty() bool {
+	return len(r.paths) == 0 && len(r.keys) == 0 && len(r.patterns) == 0
+}
+
+// responsePaths Return the paths, by response name, of the fields of the
+// response to req matching the paths or keys of r by their schema name, so
+// aliases don't get past them. unparsed when req can't be parsed to tell.
+func (r *redactor) responsePaths(ctx context.Context, req GraphqlRequest) (paths []string, unparsed bool) {
+	if len(r.paths) == 0 && len(r.keys) == 0 {
+		return nil, false
+	}
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return nil, true
+	}
+	op := doc.operation(req.OperationName)
+	if op == nil {
+		return nil, false
+	}
+	r.walkResponse(doc, op.selections, "", "", map[string]bool{}, &paths)
+	return paths, false
+}
+
+// walkResponse Add to paths the response paths of the selections, found at
+// namePath by schema names and at responsePath by response names, that are
+// redacted
+func (r *redactor) walkResponse(doc *document, selections []selection, namePath, responsePath string, visited map[string]bool, paths *[]string) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			fieldNamePath := joinPath(namePath, s.name)
+			fieldResponsePath := joinPath(responsePath, s.responseName())
+			if r.paths[fieldNamePath] || r.keys[strings.ToLower(s.name)] {
+				*paths = append(*paths, fieldResponsePath)
+				continue
+			}
+			r.walkResponse(doc, s.selections, fieldNamePath, fieldResponsePath, visited, paths)
+		case *inlineFragment:
+			r.walkResponse(doc, s.selections, namePath, responsePath, visited, paths)
+		case *fragmentSpread:
+			frag, ok := doc.fragments[s.name]
+			if !ok || visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			r.walkResponse(doc, frag.selections, namePath, responsePath, visited, paths)
+			delete(visited, s.name)
+		}
+	}
+}
+
+// redactJSON Return a redacted copy of the json document raw, also omitting
+// extraPaths
+func (r *redactor) redactJSON(raw []byte, extraPaths []string) []byte {
+	if r.empty() && len(extraPaths) == 0 {
+		return raw
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return raw
+	}
+	extra := make(map[string]bool, len(extraPaths))
+	for _, path := range extraPaths {
+		extra[path] = true
+	}
+	redacted, err := json.Marshal(r.redact(value, "", extra))
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
+
+// redact Redact the decoded json value found at path
+func (r *redactor) redact(value interface{}, path string, extra map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + "." + key
+			}
+			if r.paths[itemPath] || extra[itemPath] || r.keys[strings.ToLower(key)] {
+				v[key] = omitted
+				continue
+			}
+			v[key] = r.redact(item, itemPath, extra)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redact(item, path, extra)
+		}
+	case string:
+		for _, pattern := range r.patterns {
+			v = pattern.ReplaceAllString(v, omitted)
+		}
+		return v
+	case json.Number:
+		for _, pattern := range r.patterns {
+			if pattern.MatchString(v.String()) {
+				return omitted
+			}
+		}
+	}
+	return value
+}
@@ -0,0 +1,110 @@
+package graphqlkit
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func Test_redactor_redactJSON(t *testing.T) {
+	r := newRedactor(Redaction{
+		Paths:    []string{"input.card.number", "users.email"},
+		Keys:     []string{"password"},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`[a-z]+@[a-z.]+`), regexp.MustCompile(`^\d{16}$`)},
+	})
+	tests := []struct {
+		name      string
+		raw       string
+		extraKeys []string
+		want      string
+	}{
+		{"Nested path", `{"input":{"card":{"number":"1","cvv":2}}}`, nil, `{"input":{"card":{"cvv":2,"number":"(omitted)"}}}`},
+		{"Path through a list", `{"users":[{"email":"a","id":1},{"email":"b","id":2}]}`, nil, `{"users":[{"email":"(omitted)","id":1},{"email":"(omitted)","id":2}]}`},
+		{"Key at any depth", `{"a":{"Password":"x"},"password":"y"}`, nil, `{"a":{"Password":"(omitted)"},"password":"(omitted)"}`},
+		{"Patterns", `{"note":"mail me at a@b.com","card":4111111111111111}`, nil, `{"card":"(omitted)","note":"mail me at (omitted)"}`},
+		{"Top level keys of the method", `{"param":[1],"other":1}`, []string{"param"}, `{"other":1,"param":"(omitted)"}`},
+		{"Big numbers kept", `{"id":12345678901234567890}`, nil, `{"id":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(r.redactJSON([]byte(tt.raw), tt.extraKeys)); got != tt.want {
+				t.Errorf("redactJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggingService_RedactsWithoutChangingTheRequest(t *testing.T) {
+	//Arrange
+	config := LoggingConfig{
+		VariablesBlacklist: map[string][]string{"login": {"user"}},
+		Redaction:          Redaction{Keys: []string{"password"}},
+		Fields:             []LogField{LogVariables, LogResponse},
+	}
+	stub := &stubService{res: &graphql.Response{Data: []byte(`{"login":{"password":"secret","ok":true}}`)}}
+	variables := map[string]interface{}{
+		"user":  "me",
+		"input": map[string]interface{}{"password": "secret"},
+	}
+
+	//Act
+	got := logRequest(config, stub, GraphqlRequest{Query: "mutation { login }", Variables: variables})
+
+	//Assert
+	if strings.Contains(got, "secret") || strings.Contains(got, "me\\") {
+		t.Errorf("Should have omitted the sensitive values and returned %v\n", got)
+	}
+	if !strings.Contains(got, `\"ok\":true`) {
+		t.Errorf("Should have logged the rest of the response and returned %v\n", got)
+	}
+	if variables["user"] != "me" || variables["input"].(map[string]interface{})["password"] != "secret" {
+		t.Errorf("Shouldn't have changed the variables of the request and returned %v\n", variables)
+	}
+}
+
+func TestLoggingService_RedactsAliasedFields(t *testing.T) {
+	tests := []struct {
+		name string
+		req  GraphqlRequest
+	}{
+		{"Path", GraphqlRequest{Query: `{ pw: anyMethod(param: [1]) }`}},
+		{"Key", GraphqlRequest{Query: `query Q { user { pw: password } }`, OperationName: "Q"}},
+		{"Key in a fragment", GraphqlRequest{Query: `{ user { ...F } } fragment F on User { pw: password }`}},
+	}
+	config := LoggingConfig{
+		Redaction: Redaction{Paths: []string{"anyMethod"}, Keys: []string{"password"}},
+		Fields:    []LogField{LogResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Arrange
+			data := `{"pw":"secret"}`
+			if tt.name != "Path" {
+				data = `{"user":{"pw":"secret"}}`
+			}
+			stub := &stubService{res: &graphql.Response{Data: []byte(data)}}
+
+			//Act
+			got := logRequest(config, stub, tt.req)
+
+			//Assert
+			if strings.Contains(got, "secret") || !strings.Contains(got, `\"pw\":\"(omitted)\"`) {
+				t.Errorf("Should have omitted the aliased field and returned %v\n", got)
+			}
+		})
+	}
+}
+
+func TestNewHandlers_WithInvalidRedactionPattern_ShouldReturnError(t *testing.T) {
+	//Act
+	_, err := NewHandlers("", &queryResolver,
+		WithSchemaSources(SchemaString("schema.graphql", schema)),
+		WithLoggingRedactedPatterns("(unclosed"))
+
+	//Assert
+	if err == nil {
+		t.Error("Should have returned an error for an invalid pattern, but it didn't.\n")
+	}
+}
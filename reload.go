@@ -0,0 +1,88 @@
+package graphqlkit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ErrNotReloadable is returned when watching a Service that wasn't created
+// by NewService, LoadService or LoadServiceFromSources
+var ErrNotReloadable = errors.New("service schema can't be reloaded")
+
+// WatchService Development mode: check the schema files of s every interval
+// and swap the schema when they change, until ctx is done. If the new schema
+// is invalid the previous one is kept and the error is logged, once until
+// the files change again.
+func WatchService(ctx context.Context, s Service, interval time.Duration, logger log.Logger) error {
+	service, ok := s.(*graphqlService)
+	if !ok {
+		return ErrNotReloadable
+	}
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		failure := ""
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloaded, err := service.reload()
+				if err != nil {
+					if err.Error() != failure {
+						logger.Log("msg", "schema reload failed, keeping the previous schema", "error", err)
+					}
+					failure = err.Error()
+					continue
+				}
+				failure = ""
+				if reloaded {
+					logger.Log("msg", "schema reloaded")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// AddSchemaReload Development mode: reload the schema when its files change,
+// checking them every interval. The schema stops being watched on Close.
+func (h *Handlers) AddSchemaReload(interval time.Duration) {
+	h.reloadInterval = interval
+}
+
+// Close Stop watching the schema files
+func (h *Handlers) Close() {
+	if h.stopReload != nil {
+		h.stopReload()
+		h.stopReload = nil
+	}
+}
+
+func (h *Handlers) addSchemaReload() error {
+	if h.reloadInterval <= 0 || h.stopReload != nil {
+		return nil
+	}
+	services := make([]Service, 0, len(h.versions)+1)
+	if h.service != nil {
+		services = append(services, h.service)
+	}
+	for _, version := range h.versionNames {
+		services = append(services, h.versions[version])
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, service := range services {
+		if err := WatchService(ctx, service, h.reloadInterval, h.logger); err != nil {
+			cancel()
+			return err
+		}
+	}
+	h.stopReload = cancel
+	return nil
+}
@@ -0,0 +1,108 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type syncBuffer struct {
+	sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.Buffer.String()
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not reached in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchService_WhenSchemaChanges_ShouldReloadAndKeepLastValidSchema(t *testing.T) {
+	//Arrange
+	file, remove, err := CreateTempFile("type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	service, _, err := LoadService(file.Name(), &extendedResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchService(ctx, service, 10*time.Millisecond, log.NewLogfmtLogger(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	hasOther := func() bool {
+		return len(service.Exec(ctx, GraphqlRequest{Query: "{ other }"}).Errors) == 0
+	}
+
+	//Act
+	os.WriteFile(file.Name(), []byte("type Query {\n    anyMethod(param: [ID]!): [ID]\n    other: String\n}\n"), 0o644)
+	waitFor(t, hasOther)
+	os.WriteFile(file.Name(), []byte("type Query {\n    other(\n}\n"), 0o644)
+	waitFor(t, func() bool { return strings.Contains(buf.String(), "schema reload failed") })
+
+	//Assert
+	if !hasOther() {
+		t.Error("Should have kept the last valid schema, but it didn't.\n")
+	}
+	if schemaString := service.(*graphqlService).schemaString(); !strings.Contains(schemaString, "other: String") {
+		t.Errorf("Should have kept the last valid schema string and kept %s\n", schemaString)
+	}
+}
+
+func TestWatchService_WithInvalidSchema_ShouldLogTheFailureOnce(t *testing.T) {
+	//Arrange
+	file, remove, err := CreateTempFile("type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	service, _, err := LoadService(file.Name(), &extendedResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchService(ctx, service, 5*time.Millisecond, log.NewLogfmtLogger(&buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	//Act
+	os.WriteFile(file.Name(), []byte("type Query {\n    other(\n}\n"), 0o644)
+	waitFor(t, func() bool { return strings.Contains(buf.String(), "schema reload failed") })
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(file.Name(), []byte("type Query {\n    another(\n}\n"), 0o644)
+	waitFor(t, func() bool { return strings.Count(buf.String(), "schema reload failed") == 2 })
+	time.Sleep(50 * time.Millisecond)
+
+	//Assert
+	if count := strings.Count(buf.String(), "schema reload failed"); count != 2 {
+		t.Errorf("Should have logged each invalid schema once and logged %d times\n %v", count, buf.String())
+	}
+}
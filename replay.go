@@ -0,0 +1,264 @@
+package graphqlkit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"time"
+)
+
+// maxCapturedLine The longest line ReadCaptured accepts
+const maxCapturedLine = 16 << 20
+
+// ReplayResult How a captured request went when sent again
+type ReplayResult struct {
+	Captured CapturedRequest
+	Response json.RawMessage
+	Status   int
+	Took     time.Duration
+	// Diffs The status, when it changed, and the paths of the response whose
+	// values changed, with both values
+	Diffs []string
+	// Skipped Why the request wasn't sent, empty when it was: values of it
+	// were omitted from the capture, see CapturedRequest.Redacted, or it's a
+	// mutation, see ReplayMutations
+	Skipped string
+	Err     error
+}
+
+// Changed Report if the response changed or couldn't be obtained
+func (r ReplayResult) Changed() bool {
+	return r.Err != nil || len(r.Diffs) > 0
+}
+
+// Replayer Send captured requests again, see NewHandlerReplayer and
+// NewURLReplayer
+type Replayer interface {
+	Replay(ctx context.Context, captured CapturedRequest) ReplayResult
+}
+
+// ReplayOption Configure a Replayer
+type ReplayOption func(*replayer)
+
+// ReplayMutations Send the mutations again too, running them once more on
+// the target. They are skipped by default, as are the operations that can't
+// be parsed.
+func ReplayMutations() ReplayOption {
+	return func(r *replayer) {
+		r.mutations = true
+	}
+}
+
+type replayer struct {
+	do        func(r *http.Request) (*http.Response, error)
+	mutations bool
+}
+
+func newReplayer(do func(r *http.Request) (*http.Response, error), opts []ReplayOption) Replayer {
+	r := &replayer{do: do}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// NewHandlerReplayer Replay the requests against h, usually Handlers.Handler
+func NewHandlerReplayer(h http.Handler, opts ...ReplayOption) Replayer {
+	return newReplayer(func(r *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result(), nil
+	}, opts)
+}
+
+// NewURLReplayer Replay the requests against the graphql endpoint at url,
+// with http.DefaultClient when client is nil. The time replayed includes the
+// network, which the captured time doesn't.
+func NewURLReplayer(client *http.Client, url string, opts ...ReplayOption) Replayer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newReplayer(func(r *http.Request) (*http.Response, error) {
+		target, err := http.NewRequestWithContext(r.Context(), r.Method, url, r.Body)
+		if err != nil {
+			return nil, err
+		}
+		target.Header = r.Header
+		return client.Do(target)
+	}, opts)
+}
+
+func (rp *replayer) Replay(ctx context.Context, captured CapturedRequest) ReplayResult {
+	result := ReplayResult{Captured: captured}
+	if captured.Redacted() {
+		result.Skipped = "values omitted from the capture"
+		return result
+	}
+	if !rp.mutations && !captured.readOnly(ctx) {
+		result.Skipped = "mutation"
+		return result
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"query":         captured.Query,
+		"operationName": captured.OperationName,
+		"variables":     captured.Variables,
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/graphql", bytes.NewReader(body))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range captured.Headers {
+		req.Header.Set(name, value)
+	}
+	begin := time.Now()
+	resp, err := rp.do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Response, err = ioutil.ReadAll(resp.Body)
+	result.Took = time.Since(begin)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if captured.Status != 0 && captured.Status != result.Status {
+		result.Diffs = append(result.Diffs, fmt.Sprintf("status: %d != %d", captured.Status, result.Status))
+	}
+	diffs, err := diffResponses(captured.Response, result.Response)
+	result.Diffs, result.Err = append(result.Diffs, diffs...), err
+	return result
+}
+
+// readOnly Tell if the operation of c is a query or a subscription, false
+// when it can't be parsed
+func (c CapturedRequest) readOnly(ctx context.Context) bool {
+	doc, err := documentFor(ctx, c.Query)
+	if err != nil {
+		return false
+	}
+	op := doc.operation(c.OperationName)
+	return op != nil && op.kind != "mutation"
+}
+
+// ReadCaptured Call fn with each request of a capture
+func ReadCaptured(r io.Reader, fn func(CapturedRequest) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxCapturedLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var captured CapturedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &captured); err != nil {
+			return fmt.Errorf("capture line %d: %w", line, err)
+		}
+		if err := fn(captured); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Replay Send every request of the capture r again with replayer, reporting
+// each result to fn
+func Replay(ctx context.Context, r io.Reader, replayer Replayer, fn func(ReplayResult)) error {
+	return ReadCaptured(r, func(captured CapturedRequest) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fn(replayer.Replay(ctx, captured))
+		return nil
+	})
+}
+
+// diffResponses Compare the json responses, the values omitted from the
+// capture match anything
+func diffResponses(captured, replayed []byte) ([]string, error) {
+	var want, got interface{}
+	if err := decodeJSONNumbers(captured, &want); err != nil {
+		return nil, fmt.Errorf("captured response: %w", err)
+	}
+	if err := decodeJSONNumbers(replayed, &got); err != nil {
+		return nil, fmt.Errorf("replayed response: %w", err)
+	}
+	var diffs []string
+	diffValues("", want, got, &diffs)
+	return diffs, nil
+}
+
+func decodeJSONNumbers(raw []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func diffValues(path string, want, got interface{}, diffs *[]string) {
+	if want == omitted {
+		return
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diffValues(joinPath(path, key), w[key], g[key], diffs)
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			break
+		}
+		for i := range w {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), w[i], g[i], diffs)
+		}
+		return
+	default:
+		if want == got {
+			return
+		}
+	}
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if path == "" {
+		path = "."
+	}
+	*diffs = append(*diffs, fmt.Sprintf("%s: %s != %s", path, wantJSON, gotJSON))
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
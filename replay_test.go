@@ -0,0 +1,135 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type renamedResolver struct {
+	sensitiveResolver
+}
+
+func (r *renamedResolver) Account(args struct{ Token *string }) *sensitiveAccount {
+	return &sensitiveAccount{Name: "jane", Email: "jane@example.com"}
+}
+
+type payCountingResolver struct {
+	sensitiveResolver
+	pays int
+}
+
+func (r *payCountingResolver) Pay(args struct {
+	Input struct {
+		Amount int32
+		Card   struct{ Holder, Number string }
+	}
+}) *sensitiveAccount {
+	r.pays++
+	return r.sensitiveResolver.Pay(args)
+}
+
+func TestReplay(t *testing.T) {
+	//Arrange
+	var capture bytes.Buffer
+	serveCaptured(captureHandler(t, &sensitiveResolver{}, WithCapture(&capture)),
+		`{"query":"{ account { name email } }"}`)
+	serveCaptured(captureHandler(t, &sensitiveResolver{}, WithCapture(&capture)), auditedMutation)
+	changed := captureHandler(t, &renamedResolver{})
+	server := httptest.NewServer(changed)
+	defer server.Close()
+
+	//Act
+	var local, remote []ReplayResult
+	err := Replay(context.Background(), bytes.NewReader(capture.Bytes()), NewHandlerReplayer(changed), func(r ReplayResult) {
+		local = append(local, r)
+	})
+	Replay(context.Background(), bytes.NewReader(capture.Bytes()), NewURLReplayer(server.Client(), server.URL), func(r ReplayResult) {
+		remote = append(remote, r)
+	})
+
+	//Assert
+	if err != nil || len(local) != 2 {
+		t.Fatalf("Should have replayed both requests and returned %v %v\n", local, err)
+	}
+	if want := []string{`data.account.name: "john" != "jane"`}; !reflect.DeepEqual(local[0].Diffs, want) {
+		t.Errorf("Should have found the changed name, ignoring the omitted email, and returned %v\n", local[0].Diffs)
+	}
+	if local[1].Skipped == "" || local[1].Changed() {
+		t.Errorf("Should have skipped the mutation with omitted variables and returned %+v\n", local[1])
+	}
+	if len(remote) != 2 || !reflect.DeepEqual(remote[0].Diffs, local[0].Diffs) || remote[1].Skipped == "" {
+		t.Errorf("Should have replayed the same against the url and returned %v\n", remote)
+	}
+}
+
+func TestReadCaptured_InvalidLine(t *testing.T) {
+	//Act
+	err := ReadCaptured(strings.NewReader("{}\nnot json\n"), func(CapturedRequest) error { return nil })
+
+	//Assert
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Should have failed at line 2 and returned %v\n", err)
+	}
+}
+
+func TestReplay_Mutations(t *testing.T) {
+	//Arrange
+	captured := CapturedRequest{
+		Query:    `mutation { pay(input: {amount: 1, card: {holder: "john", number: "1"}}) { name } }`,
+		Response: []byte(`{"data":{"pay":{"name":"john"}}}`),
+	}
+	resolver := &payCountingResolver{}
+	handler := captureHandler(t, resolver)
+
+	//Act
+	skipped := NewHandlerReplayer(handler).Replay(context.Background(), captured)
+	replayed := NewHandlerReplayer(handler, ReplayMutations()).Replay(context.Background(), captured)
+
+	//Assert
+	if skipped.Skipped != "mutation" || replayed.Skipped != "" || replayed.Changed() || resolver.pays != 1 {
+		t.Errorf("Should have replayed the mutation only when asked and returned %+v %+v, paid %d times\n", skipped, replayed, resolver.pays)
+	}
+}
+
+func TestReplay_StatusChanged(t *testing.T) {
+	//Arrange
+	captured := CapturedRequest{
+		Query:    "{ account { name } }",
+		Response: []byte(`{"data":{"account":{"name":"john"}}}`),
+		Status:   http.StatusBadRequest,
+	}
+
+	//Act
+	result := NewHandlerReplayer(captureHandler(t, &sensitiveResolver{})).Replay(context.Background(), captured)
+
+	//Assert
+	if want := []string{"status: 400 != 200"}; result.Status != http.StatusOK || !reflect.DeepEqual(result.Diffs, want) {
+		t.Errorf("Should have found the changed status and returned %v %v\n", result.Status, result.Diffs)
+	}
+}
+
+func TestCapturedRequest_Redacted(t *testing.T) {
+	tests := []struct {
+		name     string
+		captured CapturedRequest
+		want     bool
+	}{
+		{"nothing omitted", CapturedRequest{Query: "{ a }", Variables: []byte(`{"id":1}`)}, false},
+		{"omitted variable", CapturedRequest{Query: "{ a }", Variables: []byte(`{"token":"(omitted)"}`)}, true},
+		{"omitted part of a variable", CapturedRequest{Query: "{ a }", Variables: []byte(`{"note":"card (omitted)"}`)}, true},
+		{"unparsable query", CapturedRequest{Query: "{ a", Variables: []byte(`"(omitted)"`)}, true},
+		{"omitted literal", CapturedRequest{Query: `{ a(token: "(omitted)") }`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.captured.Redacted(); got != tt.want {
+				t.Errorf("Redacted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package graphqlkit
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// SchemaError Describe why a schema could not be loaded or parsed
+type SchemaError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	// MissingResolver is true when the schema is valid but the resolver
+	// doesn't implement Type.Field
+	MissingResolver bool
+	Type            string
+	Field           string
+	Err             error
+}
+
+func (e *SchemaError) Error() string {
+	str := "graphql schema"
+	if e.File != "" {
+		str += " " + e.File
+	}
+	if e.Line > 0 {
+		str += fmt.Sprintf(":%d:%d", e.Line, e.Column)
+	}
+	return str + ": " + e.Message
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+var missingResolverRegexp = regexp.MustCompile(`does not resolve "([^"]+)": missing method for field "([^"]+)"`)
+
+// LoadSchema Read the schema file, returning a *SchemaError on failure
+func LoadSchema(schemaFilename string) (string, error) {
+	schemaBytes, err := ioutil.ReadFile(schemaFilename)
+	if err != nil {
+		return "", &SchemaError{File: schemaFilename, Message: err.Error(), Err: err}
+	}
+	return string(schemaBytes), nil
+}
+
+// ParseSchema Parse the schema and attach the resolver to it. Failures are
+// reported as *SchemaError with the location of the problem, file is only
+// used to describe where the schema came from.
+func ParseSchema(schemaString, file string, resolver interface{}, opts ...graphql.SchemaOpt) (*graphql.Schema, error) {
+	return ParseSchemaDocuments([]SchemaDocument{{file, schemaString}}, resolver, opts...)
+}
+
+// ParseSchemaDocuments Parse the concatenation of docs and attach the resolver
+// to it, reporting failures with the document and line where they happened.
+// Field resolvers and string descriptions are always enabled, opts are
+// applied after them.
+func ParseSchemaDocuments(docs []SchemaDocument, resolver interface{}, extraOpts ...graphql.SchemaOpt) (*graphql.Schema, error) {
+	schemaString, locate := joinSchemaDocuments(docs)
+	opts := append([]graphql.SchemaOpt{graphql.UseFieldResolvers(), graphql.UseStringDescriptions()}, extraOpts...)
+	inspect, err := graphql.ParseSchema(schemaString, nil, opts...)
+	if err != nil {
+		return nil, newSchemaError(err).locate(locate)
+	}
+	schema, err := graphql.ParseSchema(schemaString, resolver, opts...)
+	if err != nil {
+		return nil, newResolverError(inspect.ASTSchema(), err).locate(locate)
+	}
+	return schema, nil
+}
+
+func (e *SchemaError) locate(locate func(line int) (string, int)) *SchemaError {
+	e.File, e.Line = locate(e.Line)
+	return e
+}
+
+func newSchemaError(err error) *SchemaError {
+	schemaErr := &SchemaError{Message: err.Error(), Err: err}
+	var queryErr *gqlerrors.QueryError
+	if errors.As(err, &queryErr) {
+		schemaErr.Message = queryErr.Message
+		if len(queryErr.Locations) > 0 {
+			schemaErr.Line = queryErr.Locations[0].Line
+			schemaErr.Column = queryErr.Locations[0].Column
+		}
+	}
+	return schemaErr
+}
+
+func newResolverError(ast *types.Schema, err error) *SchemaError {
+	schemaErr := newSchemaError(err)
+	sm := missingResolverRegexp.FindStringSubmatch(err.Error())
+	if sm == nil {
+		return schemaErr
+	}
+	schemaErr.MissingResolver = true
+	schemaErr.Type, schemaErr.Field = sm[1], sm[2]
+	if obj, ok := ast.Types[schemaErr.Type].(*types.ObjectTypeDefinition); ok {
+		if field := obj.Fields.Get(schemaErr.Field); field != nil {
+			schemaErr.Line = field.Loc.Line
+			schemaErr.Column = field.Loc.Column
+		} else {
+			schemaErr.Line = obj.Loc.Line
+			schemaErr.Column = obj.Loc.Column
+		}
+	}
+	return schemaErr
+}
@@ -0,0 +1,165 @@
+package graphqlkit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+func TestLoadService_WithoutFile_ShouldReturnSchemaError(t *testing.T) {
+	//Act
+	_, _, err := LoadService("does-not-exist.graphql", &queryResolver)
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.File != "does-not-exist.graphql" {
+		t.Errorf("Should have returned a SchemaError with the file and returned %v\n", err)
+	}
+}
+
+func TestParseSchema_WithSyntaxError_ShouldReportLineAndColumn(t *testing.T) {
+	//Arrange
+	invalid := "type Query {\n  anyMethod(param: [ID]!): [ID]\n  broken(\n}"
+
+	//Act
+	_, err := ParseSchema(invalid, "schema.graphql", &queryResolver)
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Should have returned a SchemaError and returned %v\n", err)
+	}
+	if schemaErr.Line != 4 || schemaErr.Column == 0 || schemaErr.MissingResolver {
+		t.Errorf("Should have reported line 4 of a syntax error and reported %+v\n", schemaErr)
+	}
+}
+
+func TestParseSchema_WithFieldWithoutResolver_ShouldReportMissingResolver(t *testing.T) {
+	//Arrange
+	withExtraField := "type Query {\n  anyMethod(param: [ID]!): [ID]\n  other: String\n}"
+
+	//Act
+	_, err := ParseSchema(withExtraField, "schema.graphql", &queryResolver)
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Should have returned a SchemaError and returned %v\n", err)
+	}
+	if !schemaErr.MissingResolver || schemaErr.Type != "Query" || schemaErr.Field != "other" || schemaErr.Line != 3 {
+		t.Errorf("Should have reported the missing resolver of Query.other and reported %+v\n", schemaErr)
+	}
+}
+
+type nodeQueryResolver struct{}
+
+type nodeResolver struct{}
+
+func (r *nodeQueryResolver) Node() *nodeResolver {
+	return &nodeResolver{}
+}
+
+func (r *nodeResolver) ID() graphql.ID {
+	return "1"
+}
+
+func TestParseSchema_WithoutConversionMethod_ShouldNotReportAMissingField(t *testing.T) {
+	//Arrange
+	withInterface := "type Query {\n  node: Node\n}\ninterface Node {\n  id: ID!\n}\ntype User implements Node {\n  id: ID!\n}"
+
+	//Act
+	_, err := ParseSchema(withInterface, "schema.graphql", &nodeQueryResolver{})
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Should have returned a SchemaError and returned %v\n", err)
+	}
+	if schemaErr.MissingResolver || schemaErr.Field != "" || !strings.Contains(schemaErr.Message, `"ToUser"`) {
+		t.Errorf("Should have reported the missing conversion method, not a field, and reported %+v\n", schemaErr)
+	}
+}
+
+var splitSchema = fstest.MapFS{
+	"schema.graphql":        {Data: []byte("schema {\n\tquery: Query\n\tmutation: Mutation\n}\n")},
+	"query/query.graphql":   {Data: []byte("type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n")},
+	"mutation/mutation.gql": {Data: []byte("type Mutation {\n\tanyMethod2(param: [ID]!): Boolean\n}\n")},
+	"docs/README.md":        {Data: []byte("not a schema")},
+}
+
+func TestLoadServiceFromSources_WithFS_ShouldJoinAllSchemaFiles(t *testing.T) {
+	//Act
+	_, schemaString, err := LoadServiceFromSources(&queryResolver, []SchemaSource{SchemaFS(splitSchema)})
+
+	//Assert
+	if err != nil {
+		t.Fatalf("Should have loaded the schema and returned %v\n", err)
+	}
+	if strings.Contains(schemaString, "not a schema") {
+		t.Error("Should have read only schema files, but it didn't.\n")
+	}
+}
+
+func TestLoadServiceFromSources_WithExtendType_ShouldLoad(t *testing.T) {
+	//Arrange
+	extended := "type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n"
+	extension := "extend type Query {\n    other: String\n}\n"
+	resolver := &extendedResolver{}
+
+	//Act
+	_, _, err := LoadServiceFromSources(resolver, []SchemaSource{
+		SchemaString("query.graphql", extended), SchemaString("extension.graphql", extension)})
+
+	//Assert
+	if err != nil {
+		t.Errorf("Should have loaded the extended schema and returned %v\n", err)
+	}
+}
+
+func TestLoadServiceFromSources_WithDuplicatedType_ShouldReportBothFiles(t *testing.T) {
+	//Arrange
+	query := "type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n"
+
+	//Act
+	_, _, err := LoadServiceFromSources(&queryResolver, []SchemaSource{
+		SchemaString("a.graphql", query), SchemaString("b.graphql", "\n"+query)})
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Should have returned a SchemaError and returned %v\n", err)
+	}
+	if schemaErr.File != "b.graphql" || schemaErr.Line != 2 || !strings.Contains(schemaErr.Message, "a.graphql:1:6") {
+		t.Errorf("Should have reported the duplicated Query and reported %v\n", schemaErr)
+	}
+}
+
+func TestLoadServiceFromSources_WithSyntaxErrorInSecondFile_ShouldReportItsLine(t *testing.T) {
+	//Arrange
+	query := "type Query {\n    anyMethod(param: [ID]!): [ID]\n}\n"
+	broken := "type Other {\n  field(\n}\n"
+
+	//Act
+	_, _, err := LoadServiceFromSources(&queryResolver, []SchemaSource{
+		SchemaString("query.graphql", query), SchemaString("broken.graphql", broken)})
+
+	//Assert
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Should have returned a SchemaError and returned %v\n", err)
+	}
+	if schemaErr.File != "broken.graphql" || schemaErr.Line != 3 {
+		t.Errorf("Should have reported line 3 of broken.graphql and reported %v\n", schemaErr)
+	}
+}
+
+type extendedResolver struct {
+	anyResolver
+}
+
+func (r *extendedResolver) Other() *string {
+	return nil
+}
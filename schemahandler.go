@@ -0,0 +1,142 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	gokitjwt "github.com/go-kit/kit/auth/jwt"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// FingerprintHeader Header with the SHA-256 fingerprint of the schema SDL
+const FingerprintHeader = "X-Schema-Fingerprint"
+
+var (
+	errSchemaForbidden    = errors.New("schema is not allowed")
+	errSchemaNotFound     = errors.New("schema not found")
+	errSchemaJSONDisabled = errors.New("introspection json is disabled")
+)
+
+type schemaRequest struct {
+	service     Service
+	json        bool
+	ifNoneMatch string
+}
+
+type schemaResponse struct {
+	sdl         string
+	json        []byte
+	fingerprint string
+	notModified bool
+}
+
+// AddSchemaJSON Allow SchemaHandler to answer with the introspection result
+// as json, when requested with ?format=json or Accept: application/json
+func (h *Handlers) AddSchemaJSON() {
+	h.schemaJSON = true
+}
+
+// SchemaHandler Return a handler serving the SDL of the schema, printed from
+// the parsed schema so it is normalized, with its SHA-256 fingerprint in the
+// X-Schema-Fingerprint and ETag headers. It requires the same jwt token and
+// introspection rule as the graphql handler.
+func (h *Handlers) SchemaHandler() http.Handler {
+	schemaEndpoint := h.makeSchemaEndpoint()
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(schemaErrorEncoder),
+	}
+	if h.logger != nil {
+		options = append(options, httptransport.ServerErrorLogger(h.logger))
+	}
+	if h.authenticationEnabled() {
+		options = append(options, httptransport.ServerBefore(gokitjwt.HTTPToContext()))
+		schemaEndpoint = MakeAuthenticationEndPoint(schemaEndpoint, h.key, h.method, h.claims)
+	}
+	return httptransport.NewServer(
+		schemaEndpoint,
+		h.decodeSchemaRequest,
+		encodeSchemaResponse,
+		options...,
+	)
+}
+
+func (h *Handlers) makeSchemaEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(schemaRequest)
+		if h.introspectionRule != nil && !h.introspectionRule(ctx) {
+			return nil, errSchemaForbidden
+		}
+		service, ok := req.service.(*graphqlService)
+		if !ok {
+			return nil, errSchemaNotFound
+		}
+		loaded := service.loaded()
+		sdl, fingerprint := loaded.printed()
+		res := schemaResponse{sdl: sdl, fingerprint: "sha256:" + fingerprint}
+		if req.ifNoneMatch == `"`+res.fingerprint+`"` {
+			res.notModified = true
+			return res, nil
+		}
+		if req.json {
+			introspection, err := loaded.schema.ToJSON()
+			if err != nil {
+				return nil, err
+			}
+			res.json = introspection
+		}
+		return res, nil
+	}
+}
+
+func (h *Handlers) decodeSchemaRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := schemaRequest{ifNoneMatch: r.Header.Get("If-None-Match")}
+	req.json = r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+	if req.json && !h.schemaJSON {
+		return nil, errSchemaJSONDisabled
+	}
+	req.service, _ = h.serviceFor(r)
+	return req, nil
+}
+
+func encodeSchemaResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	res := response.(schemaResponse)
+	w.Header().Set(FingerprintHeader, res.fingerprint)
+	w.Header().Set("ETag", `"`+res.fingerprint+`"`)
+	if res.notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	if res.json != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write(res.json)
+		return err
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, err := w.Write([]byte(res.sdl))
+	return err
+}
+
+func schemaErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	var code int
+	switch err {
+	case errSchemaForbidden:
+		code = http.StatusForbidden
+	case errSchemaNotFound:
+		code = http.StatusNotFound
+	case errSchemaJSONDisabled:
+		code = http.StatusBadRequest
+	default:
+		authErrorEncoder(ctx, err, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,157 @@
+package graphqlkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func TestPrintSchema_ShouldNormalizeTheSchema(t *testing.T) {
+	//Arrange
+	sdl := `
+"""Root"""
+type Query { b(first: Int = 10): [Item!]!  a: Kind @deprecated }
+# comment
+enum Kind { ONE TWO }
+type Item implements Node { id: ID! }
+interface Node { id: ID! }
+input Filter { name: String = "x" }
+extend type Query { c(filter: Filter): Int }`
+	parsed, err := ParseSchema(sdl, "schema.graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Act
+	printed := PrintSchema(parsed)
+
+	//Assert
+	expected := `input Filter {
+	name: String = "x"
+}
+
+type Item implements Node {
+	id: ID!
+}
+
+enum Kind {
+	ONE
+	TWO
+}
+
+interface Node {
+	id: ID!
+}
+
+"""Root"""
+type Query {
+	b(first: Int = 10): [Item!]!
+	a: Kind @deprecated(reason: "No longer supported")
+	c(filter: Filter): Int
+}
+`
+	if printed != expected {
+		t.Errorf("Should have printed\n%s\nand printed\n%s", expected, printed)
+	}
+	if _, err := ParseSchema(printed, "printed.graphql", nil); err != nil {
+		t.Errorf("Should have printed a valid schema, but it returned %v\n", err)
+	}
+}
+
+func makeSchemaHandler(t *testing.T, opts ...Option) http.Handler {
+	file, remove, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remove()
+	h, err := NewHandlers(file.Name(), &queryResolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.SchemaHandler()
+}
+
+func TestSchemaHandler_ShouldReturnSDLWithFingerprint(t *testing.T) {
+	//Arrange
+	handler := makeSchemaHandler(t)
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/schema", nil))
+
+	//Assert
+	CheckResponseOk(resp, t)
+	fingerprint := resp.Header().Get(FingerprintHeader)
+	if !strings.HasPrefix(fingerprint, "sha256:") || len(fingerprint) != len("sha256:")+64 {
+		t.Errorf("Should have returned the fingerprint and returned %q\n", fingerprint)
+	}
+	if !strings.Contains(resp.Body.String(), "anyMethod2(param: [ID]!): Boolean") {
+		t.Errorf("Should have returned the SDL and returned %s\n", resp.Body.String())
+	}
+
+	//Act
+	req := httptest.NewRequest(http.MethodGet, "/schema", nil)
+	req.Header.Set("If-None-Match", `"`+fingerprint+`"`)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	if resp.Code != http.StatusNotModified {
+		t.Errorf("Should have returned 304 for the same fingerprint and returned %d\n", resp.Code)
+	}
+}
+
+func TestSchemaHandler_WithJSON_ShouldReturnIntrospection(t *testing.T) {
+	//Arrange
+	handler := makeSchemaHandler(t, WithSchemaJSON())
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/schema?format=json", nil))
+
+	//Assert
+	CheckResponseOk(resp, t)
+	if !strings.Contains(resp.Body.String(), `"__schema"`) {
+		t.Errorf("Should have returned the introspection and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestSchemaHandler_WithAuthenticationWithoutToken_ShouldReturnUnauthorized(t *testing.T) {
+	//Arrange
+	handler := makeSchemaHandler(t,
+		WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} }))
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/schema", nil))
+
+	//Assert
+	CheckResponseUnauthorized(resp, t, "token up for parsing was not passed through the context")
+}
+
+func TestPrintSchema_WithDescriptionEndingInQuote_ShouldPrintAValidSchema(t *testing.T) {
+	//Arrange
+	sdl := "\"Say \\\"hi\\\"\"\ntype Query { a: Int }"
+	parsed, err := ParseSchema(sdl, "schema.graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Act
+	printed := PrintSchema(parsed)
+
+	//Assert
+	reparsed, err := ParseSchema(printed, "printed.graphql", nil)
+	if err != nil {
+		t.Fatalf("Should have printed a valid schema, but it returned %v\n%s", err, printed)
+	}
+	if again := PrintSchema(reparsed); again != printed {
+		t.Errorf("Should have printed the same schema again and printed\n%s\nthen\n%s", printed, again)
+	}
+	if !strings.Contains(printed, `Say "hi"`) {
+		t.Errorf("Should have kept the description and printed\n%s", printed)
+	}
+}
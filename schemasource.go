@@ -0,0 +1,312 @@
+package graphqlkit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SchemaDocument A named piece of a graphql schema
+type SchemaDocument struct {
+	Name    string
+	Content string
+}
+
+// SchemaSource Provide the documents that together make a graphql schema
+type SchemaSource interface {
+	Documents() ([]SchemaDocument, error)
+}
+
+// SchemaSourceFunc Adapt a function to a SchemaSource
+type SchemaSourceFunc func() ([]SchemaDocument, error)
+
+// Documents Call f
+func (f SchemaSourceFunc) Documents() ([]SchemaDocument, error) {
+	return f()
+}
+
+var schemaExtensions = []string{".graphql", ".graphqls", ".gql"}
+
+// SchemaFile Read the schema from a single file
+func SchemaFile(filename string) SchemaSource {
+	return SchemaSourceFunc(func() ([]SchemaDocument, error) {
+		content, err := LoadSchema(filename)
+		if err != nil {
+			return nil, err
+		}
+		return []SchemaDocument{{filename, content}}, nil
+	})
+}
+
+// SchemaGlob Read the schema from all files matching pattern, sorted by name
+func SchemaGlob(pattern string) SchemaSource {
+	return SchemaSourceFunc(func() ([]SchemaDocument, error) {
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, &SchemaError{File: pattern, Message: err.Error(), Err: err}
+		}
+		if len(filenames) == 0 {
+			return nil, &SchemaError{File: pattern, Message: "no schema file found"}
+		}
+		return readSchemaFiles(filenames, os.ReadFile)
+	})
+}
+
+// SchemaDir Read the schema from all .graphql, .graphqls and .gql files
+// found in dir and its subdirectories
+func SchemaDir(dir string) SchemaSource {
+	return SchemaSourceFunc(func() ([]SchemaDocument, error) {
+		docs, err := SchemaFS(os.DirFS(dir)).Documents()
+		for i := range docs {
+			docs[i].Name = filepath.Join(dir, filepath.FromSlash(docs[i].Name))
+		}
+		if schemaErr, ok := err.(*SchemaError); ok {
+			schemaErr.File = filepath.Join(dir, filepath.FromSlash(schemaErr.File))
+		}
+		return docs, err
+	})
+}
+
+// SchemaFS Read the schema from fsys, e.g. an embed.FS. Without patterns all
+// .graphql, .graphqls and .gql files are read, otherwise the files matching
+// the fs.Glob patterns.
+func SchemaFS(fsys fs.FS, patterns ...string) SchemaSource {
+	return SchemaSourceFunc(func() ([]SchemaDocument, error) {
+		var filenames []string
+		if len(patterns) == 0 {
+			err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && isSchemaFile(name) {
+					filenames = append(filenames, name)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, &SchemaError{File: ".", Message: err.Error(), Err: err}
+			}
+		}
+		for _, pattern := range patterns {
+			matches, err := fs.Glob(fsys, pattern)
+			if err != nil {
+				return nil, &SchemaError{File: pattern, Message: err.Error(), Err: err}
+			}
+			filenames = append(filenames, matches...)
+		}
+		if len(filenames) == 0 {
+			return nil, &SchemaError{File: strings.Join(patterns, ","), Message: "no schema file found"}
+		}
+		return readSchemaFiles(filenames, func(name string) ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		})
+	})
+}
+
+// SchemaString Use schema as is, name is used in error messages
+func SchemaString(name, schema string) SchemaSource {
+	return SchemaSourceFunc(func() ([]SchemaDocument, error) {
+		return []SchemaDocument{{name, schema}}, nil
+	})
+}
+
+func isSchemaFile(name string) bool {
+	ext := path.Ext(name)
+	for _, schemaExt := range schemaExtensions {
+		if ext == schemaExt {
+			return true
+		}
+	}
+	return false
+}
+
+func readSchemaFiles(filenames []string, readFile func(string) ([]byte, error)) ([]SchemaDocument, error) {
+	sort.Strings(filenames)
+	docs := make([]SchemaDocument, 0, len(filenames))
+	for _, filename := range filenames {
+		content, err := readFile(filename)
+		if err != nil {
+			return nil, &SchemaError{File: filename, Message: err.Error(), Err: err}
+		}
+		docs = append(docs, SchemaDocument{filename, string(content)})
+	}
+	return docs, nil
+}
+
+// LoadSchemaSources Read all sources, checking that no type is defined twice
+// and that every extended type is defined
+func LoadSchemaSources(sources ...SchemaSource) ([]SchemaDocument, error) {
+	var docs []SchemaDocument
+	for _, source := range sources {
+		sourceDocs, err := source.Documents()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, sourceDocs...)
+	}
+	if len(docs) == 0 {
+		return nil, ErrMissingSchema
+	}
+	return docs, validateSchemaDocuments(docs)
+}
+
+type schemaDefinition struct {
+	file         string
+	line, column int
+}
+
+func validateSchemaDocuments(docs []SchemaDocument) error {
+	defined := make(map[string]schemaDefinition)
+	var extensions []schemaDefinitionToken
+	for _, doc := range docs {
+		for _, def := range scanSchemaDefinitions(doc.Content) {
+			if def.extend {
+				def.file = doc.Name
+				extensions = append(extensions, def)
+				continue
+			}
+			if first, ok := defined[def.name]; ok {
+				return &SchemaError{
+					File: doc.Name, Line: def.line, Column: def.column,
+					Message: fmt.Sprintf("%s is already defined in %s:%d:%d", def.name, first.file, first.line, first.column),
+				}
+			}
+			defined[def.name] = schemaDefinition{doc.Name, def.line, def.column}
+		}
+	}
+	for _, ext := range extensions {
+		if _, ok := defined[ext.name]; !ok {
+			return &SchemaError{
+				File: ext.file, Line: ext.line, Column: ext.column,
+				Message: fmt.Sprintf("cannot extend %s, it is not defined", ext.name),
+			}
+		}
+	}
+	return nil
+}
+
+// joinSchemaDocuments Concatenate docs, returning a function that maps a
+// line of the result back to the document it came from. Without a line the
+// document is only known when there is just one.
+func joinSchemaDocuments(docs []SchemaDocument) (string, func(line int) (string, int)) {
+	var sb strings.Builder
+	starts := make([]int, len(docs))
+	line := 1
+	for i, doc := range docs {
+		starts[i] = line
+		sb.WriteString(doc.Content)
+		sb.WriteString("\n")
+		line += strings.Count(doc.Content, "\n") + 1
+	}
+	return sb.String(), func(line int) (string, int) {
+		if line <= 0 {
+			if len(docs) == 1 {
+				return docs[0].Name, line
+			}
+			return "", line
+		}
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > line }) - 1
+		if i < 0 {
+			return "", line
+		}
+		return docs[i].Name, line - starts[i] + 1
+	}
+}
+
+type schemaDefinitionToken struct {
+	name         string
+	extend       bool
+	file         string
+	line, column int
+}
+
+var schemaDefinitionKeywords = map[string]bool{
+	"type": true, "interface": true, "input": true, "enum": true,
+	"union": true, "scalar": true, "directive": true,
+}
+
+// scanSchemaDefinitions Find the top level named definitions of schema,
+// skipping comments, strings and everything inside braces
+func scanSchemaDefinitions(schema string) []schemaDefinitionToken {
+	var defs []schemaDefinitionToken
+	var prev string
+	var pending *schemaDefinitionToken
+	depth := 0
+	line, column := 1, 1
+	advance := func(n int) {
+		for _, r := range schema[:n] {
+			if r == '\n' {
+				line++
+				column = 1
+			} else {
+				column++
+			}
+		}
+		schema = schema[n:]
+	}
+	for len(schema) > 0 {
+		switch c := schema[0]; {
+		case c == '#':
+			end := strings.IndexByte(schema, '\n')
+			if end < 0 {
+				end = len(schema)
+			}
+			advance(end)
+		case strings.HasPrefix(schema, `"""`):
+			end := strings.Index(schema[3:], `"""`)
+			if end < 0 {
+				return defs
+			}
+			advance(end + 6)
+		case c == '"':
+			end := 1
+			for end < len(schema) && schema[end] != '"' && schema[end] != '\n' {
+				if schema[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(schema) {
+				end++
+			}
+			advance(end)
+		case c == '{' || c == '(':
+			depth++
+			advance(1)
+		case c == '}' || c == ')':
+			depth--
+			advance(1)
+		case c == '@' || c == '_' || isLetter(c):
+			end := 1
+			for end < len(schema) && (schema[end] == '_' || isLetter(schema[end]) || isDigit(schema[end])) {
+				end++
+			}
+			word := schema[:end]
+			if pending != nil {
+				pending.name = strings.TrimPrefix(word, "@")
+				pending.line, pending.column = line, column
+				defs = append(defs, *pending)
+				pending = nil
+			} else if depth == 0 && schemaDefinitionKeywords[word] {
+				pending = &schemaDefinitionToken{extend: prev == "extend"}
+			}
+			prev = word
+			advance(end)
+		default:
+			advance(1)
+		}
+	}
+	return defs
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,220 @@
+package graphqlkit
+
+import (
+	"sort"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+var builtinTypes = map[string]bool{
+	"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true,
+	"_Service": true,
+}
+
+var builtinDirectives = map[string]bool{
+	"include": true, "skip": true, "deprecated": true, "specifiedBy": true,
+}
+
+// PrintSchema Print the SDL of schema in a normalized form: definitions are
+// sorted by name, descriptions are block strings and builtin types and
+// directives are omitted
+func PrintSchema(schema *graphql.Schema) string {
+	return printSchema(schema.ASTSchema())
+}
+
+func printSchema(s *types.Schema) string {
+	var parts []string
+	if schemaDef := printSchemaDefinition(s); schemaDef != "" {
+		parts = append(parts, schemaDef)
+	}
+	parts = append(parts, printDefinitions(s)...)
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// printDefinitions Print each directive and type definition of s, sorted
+// by name
+func printDefinitions(s *types.Schema) []string {
+	var parts []string
+	directiveNames := make([]string, 0, len(s.Directives))
+	for name := range s.Directives {
+		if !builtinDirectives[name] {
+			directiveNames = append(directiveNames, name)
+		}
+	}
+	sort.Strings(directiveNames)
+	for _, name := range directiveNames {
+		parts = append(parts, printDirectiveDefinition(s.Directives[name]))
+	}
+	typeNames := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		if !builtinTypes[name] && !strings.HasPrefix(name, "__") {
+			typeNames = append(typeNames, name)
+		}
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		parts = append(parts, printNamedType(s.Types[name]))
+	}
+	return parts
+}
+
+// printSchemaDefinition Print the schema definition unless the root types
+// have their default names
+func printSchemaDefinition(s *types.Schema) string {
+	var sb strings.Builder
+	custom := false
+	for _, op := range []string{"query", "mutation", "subscription"} {
+		name, ok := s.EntryPointNames[op]
+		if !ok {
+			continue
+		}
+		if name != strings.ToUpper(op[:1])+op[1:] {
+			custom = true
+		}
+		sb.WriteString("\t" + op + ": " + name + "\n")
+	}
+	if !custom {
+		return ""
+	}
+	return "schema {\n" + sb.String() + "}"
+}
+
+func printNamedType(t types.NamedType) string {
+	var sb strings.Builder
+	sb.WriteString(printDescription(t.Description(), ""))
+	switch t := t.(type) {
+	case *types.ScalarTypeDefinition:
+		sb.WriteString("scalar " + t.Name + printDirectives(t.Directives))
+	case *types.ObjectTypeDefinition:
+		sb.WriteString("type " + t.Name)
+		if len(t.Interfaces) > 0 {
+			names := make([]string, len(t.Interfaces))
+			for i, intf := range t.Interfaces {
+				names[i] = intf.Name
+			}
+			sb.WriteString(" implements " + strings.Join(names, " & "))
+		}
+		sb.WriteString(printDirectives(t.Directives) + printFields(t.Fields))
+	case *types.InterfaceTypeDefinition:
+		sb.WriteString("interface " + t.Name)
+		if len(t.Interfaces) > 0 {
+			names := make([]string, len(t.Interfaces))
+			for i, intf := range t.Interfaces {
+				names[i] = intf.Name
+			}
+			sb.WriteString(" implements " + strings.Join(names, " & "))
+		}
+		sb.WriteString(printDirectives(t.Directives) + printFields(t.Fields))
+	case *types.Union:
+		names := make([]string, len(t.UnionMemberTypes))
+		for i, member := range t.UnionMemberTypes {
+			names[i] = member.Name
+		}
+		sb.WriteString("union " + t.Name + printDirectives(t.Directives) + " = " + strings.Join(names, " | "))
+	case *types.EnumTypeDefinition:
+		sb.WriteString("enum " + t.Name + printDirectives(t.Directives) + " {\n")
+		for _, v := range t.EnumValuesDefinition {
+			sb.WriteString(printDescription(v.Desc, "\t"))
+			sb.WriteString("\t" + v.EnumValue + printDirectives(v.Directives) + "\n")
+		}
+		sb.WriteString("}")
+	case *types.InputObject:
+		sb.WriteString("input " + t.Name + printDirectives(t.Directives) + " {\n")
+		for _, v := range t.Values {
+			sb.WriteString(printDescription(v.Desc, "\t"))
+			sb.WriteString("\t" + printInputValue(v) + "\n")
+		}
+		sb.WriteString("}")
+	}
+	return sb.String()
+}
+
+func printFields(fields types.FieldsDefinition) string {
+	var sb strings.Builder
+	sb.WriteString(" {\n")
+	for _, f := range fields {
+		sb.WriteString(printDescription(f.Desc, "\t"))
+		sb.WriteString("\t" + f.Name + printArguments(f.Arguments, "\t"))
+		sb.WriteString(": " + f.Type.String() + printDirectives(f.Directives) + "\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func printArguments(args types.ArgumentsDefinition, indent string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	described := false
+	printed := make([]string, len(args))
+	for i, arg := range args {
+		printed[i] = printInputValue(arg)
+		if arg.Desc != "" {
+			described = true
+		}
+	}
+	if !described {
+		return "(" + strings.Join(printed, ", ") + ")"
+	}
+	var sb strings.Builder
+	sb.WriteString("(\n")
+	for i, arg := range args {
+		sb.WriteString(printDescription(arg.Desc, indent+"\t"))
+		sb.WriteString(indent + "\t" + printed[i] + "\n")
+	}
+	sb.WriteString(indent + ")")
+	return sb.String()
+}
+
+func printInputValue(v *types.InputValueDefinition) string {
+	str := v.Name.Name + ": " + v.Type.String()
+	if v.Default != nil {
+		str += " = " + v.Default.String()
+	}
+	return str + printDirectives(v.Directives)
+}
+
+func printDirectiveDefinition(d *types.DirectiveDefinition) string {
+	str := printDescription(d.Desc, "") + "directive @" + d.Name + printArguments(d.Arguments, "")
+	if d.Repeatable {
+		str += " repeatable"
+	}
+	return str + " on " + strings.Join(d.Locations, " | ")
+}
+
+func printDirectives(directives types.DirectiveList) string {
+	var sb strings.Builder
+	for _, d := range directives {
+		sb.WriteString(" @" + d.Name.Name)
+		var args []string
+		for _, arg := range d.Arguments {
+			if arg.Value != nil {
+				args = append(args, arg.Name.Name+": "+arg.Value.String())
+			}
+		}
+		if len(args) > 0 {
+			sb.WriteString("(" + strings.Join(args, ", ") + ")")
+		}
+	}
+	return sb.String()
+}
+
+func printDescription(desc, indent string) string {
+	if desc == "" {
+		return ""
+	}
+	desc = strings.ReplaceAll(desc, `"""`, `\"""`)
+	// A quote or backslash at the end would be read with the closing quotes
+	if !strings.Contains(desc, "\n") && !strings.HasSuffix(desc, `"`) && !strings.HasSuffix(desc, `\`) {
+		return indent + `"""` + desc + `"""` + "\n"
+	}
+	lines := strings.Split(desc, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return indent + `"""` + "\n" + strings.Join(lines, "\n") + "\n" + indent + `"""` + "\n"
+}
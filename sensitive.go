@@ -0,0 +1,245 @@
+package graphqlkit
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go/types"
+)
+
+// sensitiveValues What is hidden of a request and its response for holding
+// values marked @sensitive in the schema
+type sensitiveValues struct {
+	// variables Paths of the sensitive variables
+	variables []string
+	// response Paths of the sensitive response data
+	response []string
+	// literals Spans of the query holding sensitive literal values
+	literals [][2]int
+	// unparsed The query couldn't be parsed, so its variables and response
+	// data are hidden whole
+	unparsed bool
+}
+
+// sensitivePaths Return the values of req and its response marked @sensitive
+// in schema, declared as
+//
+//	directive @sensitive on FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+func sensitivePaths(ctx context.Context, schema *types.Schema, req GraphqlRequest) sensitiveValues {
+	if _, ok := schema.Directives["sensitive"]; !ok {
+		return sensitiveValues{}
+	}
+	doc, err := documentFor(ctx, req.Query)
+	if err != nil {
+		return sensitiveValues{unparsed: true}
+	}
+	op := doc.operation(req.OperationName)
+	if op == nil {
+		return sensitiveValues{}
+	}
+	w := &sensitiveWalker{
+		schema: schema, doc: doc, variables: map[string]bool{}, literals: map[[2]int]bool{}, visited: map[string]bool{},
+	}
+	for _, def := range op.variables {
+		w.walkInputType(schema.Types[strings.Trim(def.typ, "[]!")], def.name, map[string]bool{})
+	}
+	w.walk(op.selections, schema.EntryPoints[op.kind], "")
+	values := sensitiveValues{response: w.response}
+	for name := range w.variables {
+		values.variables = append(values.variables, name)
+	}
+	for span := range w.literals {
+		values.literals = append(values.literals, span)
+	}
+	sort.Slice(values.literals, func(i, j int) bool { return values.literals[i][0] < values.literals[j][0] })
+	return values
+}
+
+// redactQuery Replace the sensitive literal values of query with a string
+func (v sensitiveValues) redactQuery(query string) string {
+	if len(v.literals) == 0 {
+		return query
+	}
+	var redacted strings.Builder
+	last := 0
+	for _, span := range v.literals {
+		redacted.WriteString(query[last:span[0]])
+		redacted.WriteString(strconv.Quote(omitted))
+		last = span[1]
+	}
+	redacted.WriteString(query[last:])
+	return redacted.String()
+}
+
+// redactVariables Return variables, as json, with the sensitive ones and the
+// ones of redactor omitted
+func (v sensitiveValues) redactVariables(r *redactor, variables json.RawMessage, extra ...string) json.RawMessage {
+	if v.unparsed {
+		return json.RawMessage(strconv.Quote(omitted))
+	}
+	return r.redactJSON(variables, append(extra, v.variables...))
+}
+
+// redactResponse Return the response data of req with the sensitive values
+// and the ones of redactor omitted
+func (v sensitiveValues) redactResponse(ctx context.Context, r *redactor, req GraphqlRequest, data json.RawMessage) json.RawMessage {
+	paths, unparsed := r.responsePaths(ctx, req)
+	if v.unparsed || unparsed {
+		return json.RawMessage(strconv.Quote(omitted))
+	}
+	return r.redactJSON(data, append(paths, v.response...))
+}
+
+type sensitiveWalker struct {
+	schema    *types.Schema
+	doc       *document
+	variables map[string]bool
+	response  []string
+	literals  map[[2]int]bool
+	visited   map[string]bool
+}
+
+func isSensitive(directives types.DirectiveList) bool {
+	return directives.Get("sensitive") != nil
+}
+
+// unwrapType Return the named type of t, without its lists and non nulls
+func unwrapType(t types.Type) types.NamedType {
+	for {
+		switch typ := t.(type) {
+		case *types.List:
+			t = typ.OfType
+		case *types.NonNull:
+			t = typ.OfType
+		default:
+			named, _ := t.(types.NamedType)
+			return named
+		}
+	}
+}
+
+// walkInputType Add the paths, below path, of the sensitive fields of the
+// input type t. inPath holds the types already crossed, for recursive inputs.
+func (w *sensitiveWalker) walkInputType(t types.NamedType, path string, inPath map[string]bool) {
+	input, ok := t.(*types.InputObject)
+	if !ok || inPath[input.Name] {
+		return
+	}
+	inPath[input.Name] = true
+	defer delete(inPath, input.Name)
+	for _, def := range input.Values {
+		fieldPath := path + "." + def.Name.Name
+		if isSensitive(def.Directives) {
+			w.variables[fieldPath] = true
+			continue
+		}
+		w.walkInputType(unwrapType(def.Type), fieldPath, inPath)
+	}
+}
+
+// walk Add the response paths of the sensitive fields of selections on parent
+func (w *sensitiveWalker) walk(selections []selection, parent types.NamedType, path string) {
+	for _, sel := range selections {
+		switch s := sel.(type) {
+		case *field:
+			w.walkField(s, parent, path)
+		case *inlineFragment:
+			typ := parent
+			if s.typeCondition != "" {
+				typ = w.schema.Types[s.typeCondition]
+			}
+			w.walk(s.selections, typ, path)
+		case *fragmentSpread:
+			frag, ok := w.doc.fragments[s.name]
+			if !ok || w.visited[s.name] {
+				continue
+			}
+			w.visited[s.name] = true
+			w.walk(frag.selections, w.schema.Types[frag.typeCondition], path)
+			delete(w.visited, s.name)
+		}
+	}
+}
+
+func (w *sensitiveWalker) walkField(f *field, parent types.NamedType, path string) {
+	var fields types.FieldsDefinition
+	switch t := parent.(type) {
+	case *types.ObjectTypeDefinition:
+		fields = t.Fields
+	case *types.InterfaceTypeDefinition:
+		fields = t.Fields
+	}
+	def := fields.Get(f.name)
+	if def == nil {
+		return
+	}
+	for _, arg := range f.arguments {
+		argDef := def.Arguments.Get(arg.name)
+		if argDef == nil {
+			continue
+		}
+		if isSensitive(argDef.Directives) {
+			w.markValue(arg.value)
+			continue
+		}
+		w.walkValue(arg.value, argDef.Type)
+	}
+	fieldPath := f.responseName()
+	if path != "" {
+		fieldPath = path + "." + fieldPath
+	}
+	if isSensitive(def.Directives) {
+		w.response = append(w.response, fieldPath)
+		return
+	}
+	w.walk(f.selections, unwrapType(def.Type), fieldPath)
+}
+
+// markValue Mark the variables and the literals used in v as sensitive
+func (w *sensitiveWalker) markValue(v *value) {
+	switch v.kind {
+	case variableValue:
+		w.variables[v.raw] = true
+	case listValue:
+		for _, item := range v.list {
+			w.markValue(item)
+		}
+	case objectValue:
+		for _, f := range v.fields {
+			w.markValue(f.value)
+		}
+	case nullValue:
+	default:
+		w.literals[[2]int{v.start, v.end}] = true
+	}
+}
+
+// walkValue Mark the variables and literals used in v for sensitive input
+// fields of t
+func (w *sensitiveWalker) walkValue(v *value, t types.Type) {
+	switch v.kind {
+	case listValue:
+		for _, item := range v.list {
+			w.walkValue(item, t)
+		}
+	case objectValue:
+		input, ok := unwrapType(t).(*types.InputObject)
+		if !ok {
+			return
+		}
+		for _, f := range v.fields {
+			def := input.Values.Get(f.name)
+			if def == nil {
+				continue
+			}
+			if isSensitive(def.Directives) {
+				w.markValue(f.value)
+				continue
+			}
+			w.walkValue(f.value, def.Type)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const sensitiveSchema = `
+directive @sensitive on FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+type Query {
+	account(token: String @sensitive): Account
+}
+type Mutation {
+	pay(input: PaymentInput!): Account
+}
+input PaymentInput {
+	amount: Int!
+	card: CardInput!
+}
+input CardInput {
+	holder: String!
+	number: String! @sensitive
+}
+type Account {
+	name: String!
+	email: String! @sensitive
+}`
+
+type sensitiveAccount struct {
+	Name  string
+	Email string
+}
+
+type sensitiveResolver struct{}
+
+func (r *sensitiveResolver) Account(args struct{ Token *string }) *sensitiveAccount {
+	return &sensitiveAccount{Name: "john", Email: "john@example.com"}
+}
+
+func (r *sensitiveResolver) Pay(args struct {
+	Input struct {
+		Amount int32
+		Card   struct{ Holder, Number string }
+	}
+}) *sensitiveAccount {
+	return &sensitiveAccount{Name: args.Input.Card.Holder, Email: "john@example.com"}
+}
+
+func Test_sensitivePaths(t *testing.T) {
+	//Arrange
+	schema := graphql.MustParseSchema(sensitiveSchema, &sensitiveResolver{}, graphql.UseFieldResolvers())
+	req := GraphqlRequest{Query: `
+mutation m($in: PaymentInput!, $n: String!) {
+	pay(input: $in) { name ...f }
+	other: pay(input: {amount: 1, card: {holder: "x", number: $n}}) { mail: email }
+}
+query q($t: String) { account(token: $t) { email } }
+fragment f on Account { email }`, OperationName: "m"}
+
+	//Act
+	sensitive := sensitivePaths(context.Background(), schema.ASTSchema(), req)
+	variables, response := sensitive.variables, sensitive.response
+
+	//Assert
+	sort.Strings(variables)
+	if want := []string{"in.card.number", "n"}; !reflect.DeepEqual(variables, want) {
+		t.Errorf("Should have found the sensitive variables %v and returned %v\n", want, variables)
+	}
+	if want := []string{"pay.email", "other.mail"}; !reflect.DeepEqual(response, want) {
+		t.Errorf("Should have found the sensitive response paths %v and returned %v\n", want, response)
+	}
+}
+
+func TestHandlers_SensitiveValuesAreNotLogged(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	h, err := NewHandlers("", &sensitiveResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", sensitiveSchema)),
+		WithSchemaOptions(graphql.UseFieldResolvers()),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"query":"mutation($in: PaymentInput!) { pay(input: $in) { name email } }",` +
+		`"variables":{"in":{"amount":10,"card":{"holder":"john","number":"4111111111111111"}}}}`
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "john@example.com") {
+		t.Errorf("Should have answered the email and returned %v\n", resp.Body.String())
+	}
+	logged := buf.String()
+	if strings.Contains(logged, "4111111111111111") || strings.Contains(logged, "john@example.com") {
+		t.Errorf("Shouldn't have logged the sensitive values and returned %v\n", logged)
+	}
+	if !strings.Contains(logged, `\"holder\":\"john\"`) {
+		t.Errorf("Should have logged the other values and returned %v\n", logged)
+	}
+}
+
+func Test_sensitivePaths_RedactQuery(t *testing.T) {
+	//Arrange
+	schema := graphql.MustParseSchema(sensitiveSchema, &sensitiveResolver{}, graphql.UseFieldResolvers())
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Sensitive argument", `{ account(token: "secret") { name } }`, `{ account(token: "(omitted)") { name } }`},
+		{"Sensitive input field", `mutation { pay(input: {amount: 1, card: {holder: "x", number: "4111"}}) { name } }`,
+			`mutation { pay(input: {amount: 1, card: {holder: "x", number: "(omitted)"}}) { name } }`},
+		{"Variable", `query($t: String) { account(token: $t) { name } }`, `query($t: String) { account(token: $t) { name } }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Act
+			sensitive := sensitivePaths(context.Background(), schema.ASTSchema(), GraphqlRequest{Query: tt.query})
+
+			//Assert
+			if got := sensitive.redactQuery(tt.query); got != tt.want {
+				t.Errorf("Should have redacted the query to %v and returned %v\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoggingService_SensitiveValuesOfUnparsableQueriesAreNotLogged(t *testing.T) {
+	//Arrange
+	service, _, err := LoadServiceFromSources(&sensitiveResolver{},
+		[]SchemaSource{SchemaString("schema.graphql", sensitiveSchema)}, graphql.UseFieldResolvers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	s := NewConfiguredLoggingService(log.NewLogfmtLogger(&buf), service, LoggingConfig{})
+	req := GraphqlRequest{
+		Query:     "query($t: String) { account(token: $t) { name email } } /* x */",
+		Variables: map[string]interface{}{"t": "secret-token"},
+	}
+
+	//Act
+	res := s.Exec(context.Background(), req)
+
+	//Assert
+	if !strings.Contains(string(res.Data), "john@example.com") {
+		t.Errorf("Should have answered the email and returned %s %v\n", res.Data, res.Errors)
+	}
+	logged := buf.String()
+	if strings.Contains(logged, "secret-token") || strings.Contains(logged, "john@example.com") {
+		t.Errorf("Shouldn't have logged the variables and response it couldn't check and returned %v\n", logged)
+	}
+}
+
+func TestHandlers_SensitiveLiteralsAreNotLogged(t *testing.T) {
+	//Arrange
+	var buf bytes.Buffer
+	h, err := NewHandlers("", &sensitiveResolver{},
+		WithSchemaSources(SchemaString("schema.graphql", sensitiveSchema)),
+		WithSchemaOptions(graphql.UseFieldResolvers()),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+		WithSlowQueryThreshold(time.Nanosecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"query":"{ account(token: \"secret-token\") { name } }"}`
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	//Act
+	h.Handler().ServeHTTP(resp, req)
+
+	//Assert
+	logged := buf.String()
+	if strings.Contains(logged, "secret-token") || !strings.Contains(logged, "slow=true") {
+		t.Errorf("Shouldn't have logged the sensitive literal in the slow query log and returned %v\n", logged)
+	}
+}
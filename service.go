@@ -2,7 +2,10 @@ package graphqlkit
 
 import (
 	"context"
-	"io/ioutil"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
 	graphql "github.com/graph-gophers/graphql-go"
 )
@@ -13,25 +16,123 @@ type Service interface {
 }
 
 type graphqlService struct {
-	schema *graphql.Schema
+	current  atomic.Value
+	resolver interface{}
+	sources  []SchemaSource
+	opts     []graphql.SchemaOpt
+	entities EntityResolvers
+	// failed The last schema that failed to parse, not parsed again by
+	// reload until it changes
+	failed string
 }
 
-// NewService Create a new graphql service, reading and resolving schema
-func NewService(schemaFilename string, resolver interface{}) (Service, string) {
-	schema, schemaString := getGraphqlSchema(schemaFilename, resolver)
-	return &graphqlService{schema}, schemaString
+type graphqlSchema struct {
+	schema       *graphql.Schema
+	schemaString string
+	sdlOnce      sync.Once
+	sdl          string
+	fingerprint  string
+	entities     *entitySchema
 }
 
-func (s *graphqlService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
-	return s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+// printed Return the normalized SDL of the schema and its SHA-256 fingerprint
+func (s *graphqlSchema) printed() (string, string) {
+	s.sdlOnce.Do(func() {
+		s.sdl = PrintSchema(s.schema)
+		s.fingerprint = fmt.Sprintf("%x", sha256.Sum256([]byte(s.sdl)))
+	})
+	return s.sdl, s.fingerprint
 }
 
-func getGraphqlSchema(schemaFilename string, res interface{}) (*graphql.Schema, string) {
-	schemaBytes, err := ioutil.ReadFile(schemaFilename)
+// NewService Create a new graphql service, reading and resolving schema.
+// It panics if the schema can't be loaded, see LoadService.
+func NewService(schemaFilename string, resolver interface{}, opts ...graphql.SchemaOpt) (Service, string) {
+	service, schemaString, err := LoadService(schemaFilename, resolver, opts...)
 	if err != nil {
 		panic(err)
 	}
-	opts := []graphql.SchemaOpt{graphql.UseFieldResolvers(), graphql.UseStringDescriptions()}
-	schemaString := string(schemaBytes)
-	return graphql.MustParseSchema(schemaString, res, opts...), schemaString
+	return service, schemaString
+}
+
+// LoadService Create a new graphql service, reading and resolving schema,
+// returning a *SchemaError if it can't be done
+func LoadService(schemaFilename string, resolver interface{}, opts ...graphql.SchemaOpt) (Service, string, error) {
+	return LoadServiceFromSources(resolver, []SchemaSource{SchemaFile(schemaFilename)}, opts...)
+}
+
+// LoadServiceFromSources Create a new graphql service with the schema made of
+// all documents of sources, returning a *SchemaError if it can't be done
+func LoadServiceFromSources(resolver interface{}, sources []SchemaSource, opts ...graphql.SchemaOpt) (Service, string, error) {
+	return loadService(&graphqlService{resolver: resolver, sources: sources, opts: opts})
+}
+
+func loadService(s *graphqlService) (Service, string, error) {
+	docs, err := LoadSchemaSources(s.sources...)
+	if err != nil {
+		return nil, "", err
+	}
+	loaded, err := s.parse(docs)
+	if err != nil {
+		return nil, "", err
+	}
+	s.current.Store(loaded)
+	return s, loaded.schemaString, nil
+}
+
+// parse Parse the schema made of docs, as a federation subgraph if the
+// service has entity resolvers
+func (s *graphqlService) parse(docs []SchemaDocument) (*graphqlSchema, error) {
+	if s.entities != nil {
+		return parseFederatedSchema(docs, s.resolver, s.entities, s.opts)
+	}
+	schema, err := ParseSchemaDocuments(docs, s.resolver, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	schemaString, _ := joinSchemaDocuments(docs)
+	return &graphqlSchema{schema: schema, schemaString: schemaString}, nil
+}
+
+func (s *graphqlService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	loaded := s.loaded()
+	if loaded.entities != nil {
+		if res, ok := loaded.entities.exec(ctx, req); ok {
+			return res
+		}
+	}
+	return loaded.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+func (s *graphqlService) loaded() *graphqlSchema {
+	return s.current.Load().(*graphqlSchema)
+}
+
+func (s *graphqlService) schema() *graphql.Schema {
+	return s.loaded().schema
+}
+
+func (s *graphqlService) schemaString() string {
+	return s.loaded().schemaString
+}
+
+// reload Read the sources again, replacing the schema if they changed and
+// are still valid. It reports whether the schema was replaced, and an
+// invalid schema only once. It's called only by the watcher of s.
+func (s *graphqlService) reload() (bool, error) {
+	docs, err := LoadSchemaSources(s.sources...)
+	if err != nil {
+		return false, err
+	}
+	schemaString, _ := joinSchemaDocuments(docs)
+	if schemaString == s.schemaString() || schemaString == s.failed {
+		return false, nil
+	}
+	loaded, err := s.parse(docs)
+	if err != nil {
+		s.failed = schemaString
+		return false, err
+	}
+	s.failed = ""
+	s.current.Store(loaded)
+	return true, nil
 }
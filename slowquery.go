@@ -0,0 +1,122 @@
+package graphqlkit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace/noop"
+	"github.com/graph-gophers/graphql-go/trace/tracer"
+)
+
+const resolverTimingsKey contextKey = "resolverTimings"
+
+// resolverTimings How long the resolvers of a request took, by type and field
+type resolverTimings struct {
+	mu     sync.Mutex
+	fields map[string]*resolverTiming
+}
+
+type resolverTiming struct {
+	name  string
+	calls int
+	took  time.Duration
+}
+
+func newResolverTimings() *resolverTimings {
+	return &resolverTimings{fields: make(map[string]*resolverTiming)}
+}
+
+func (t *resolverTimings) add(name string, took time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing, ok := t.fields[name]
+	if !ok {
+		timing = &resolverTiming{name: name}
+		t.fields[name] = timing
+	}
+	timing.calls++
+	timing.took += took
+}
+
+// String List the resolvers, slowest first, as Type.field=calls×total
+func (t *resolverTimings) String() string {
+	t.mu.Lock()
+	timings := make([]*resolverTiming, 0, len(t.fields))
+	for _, timing := range t.fields {
+		timings = append(timings, timing)
+	}
+	t.mu.Unlock()
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].took != timings[j].took {
+			return timings[i].took > timings[j].took
+		}
+		return timings[i].name < timings[j].name
+	})
+	parts := make([]string, len(timings))
+	for i, timing := range timings {
+		parts[i] = fmt.Sprintf("%s=%dx%v", timing.name, timing.calls, timing.took)
+	}
+	return strings.Join(parts, " ")
+}
+
+// timingTracer Time the resolvers of the requests whose context has
+// resolverTimings, passing everything on to next
+type timingTracer struct {
+	next tracer.Tracer
+}
+
+func newTimingTracer(next tracer.Tracer) *timingTracer {
+	if next == nil {
+		next = noop.Tracer{}
+	}
+	return &timingTracer{next: next}
+}
+
+func (t *timingTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, tracer.QueryFinishFunc) {
+	return t.next.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+}
+
+func (t *timingTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, tracer.FieldFinishFunc) {
+	ctx, finish := t.next.TraceField(ctx, label, typeName, fieldName, trivial, args)
+	timings, ok := ctx.Value(resolverTimingsKey).(*resolverTimings)
+	if !ok || trivial {
+		return ctx, finish
+	}
+	begin := time.Now()
+	return ctx, func(err *errors.QueryError) {
+		timings.add(typeName+"."+fieldName, time.Since(begin))
+		finish(err)
+	}
+}
+
+func (t *timingTracer) TraceValidation(ctx context.Context) tracer.ValidationFinishFunc {
+	if v, ok := t.next.(tracer.ValidationTracer); ok {
+		return v.TraceValidation(ctx)
+	}
+	return func([]*errors.QueryError) {}
+}
+
+// probeTracer Marks the tracer of a schema no option replaced
+type probeTracer struct {
+	noop.Tracer
+}
+
+// setsTracer Tell if any of opts sets the tracer of the schema, applying
+// them to a probe schema since graphql-go doesn't expose it
+func setsTracer(opts []graphql.SchemaOpt) bool {
+	probe, err := graphql.ParseSchema("type Query { probe: Int }", nil,
+		append([]graphql.SchemaOpt{graphql.Tracer(probeTracer{})}, opts...)...)
+	if err != nil {
+		return false
+	}
+	t := reflect.ValueOf(probe).Elem().FieldByName("tracer")
+	return t.IsValid() && (t.IsNil() || t.Elem().Type() != reflect.TypeOf(probeTracer{}))
+}
@@ -1,44 +1,206 @@
 package graphqlkit
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// DefaultMaxBodyBytes How long the request bodies may be, unless configured
+// with AddMaxBodyBytes
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+const requestBodyKey contextKey = "requestBody"
+
+var (
+	errBadRequest          = errors.New("bad request")
+	errUnsupportedEncoding = errors.New("unsupported content encoding")
+	errInvalidData         = errors.New("invalid response data")
 )
 
-var errBadRequest = errors.New("bad request")
+// requestError A request that can't be executed, answered with its status
+type requestError struct {
+	code int
+	err  error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) StatusCode() int {
+	return e.code
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// requestBody The body of a request, read once by bodyToCtx
+type requestBody struct {
+	raw []byte
+	req GraphqlRequest
+	err error
+}
 
-func decodeGraphqlRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var params GraphqlRequest
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		fmt.Print(err)
-		return nil, errBadRequest
+// readRequestBody Read and decode up to maxBytes of the body of r, counted
+// after decompressing it
+func readRequestBody(r *http.Request, maxBytes int64) *requestBody {
+	body := &requestBody{}
+	reader, err := decompressedBody(r)
+	if errors.Is(err, errUnsupportedEncoding) {
+		body.err = &requestError{http.StatusUnsupportedMediaType, fmt.Errorf("%w %q", err, r.Header.Get("Content-Encoding"))}
+		return body
 	}
+	if err != nil {
+		body.err = &requestError{http.StatusBadRequest, fmt.Errorf("%w: decompressing body: %v", errBadRequest, err)}
+		return body
+	}
+	defer reader.Close()
+	raw, err := ioutil.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		body.err = &requestError{http.StatusBadRequest, fmt.Errorf("%w: reading body: %v", errBadRequest, err)}
+		return body
+	}
+	if int64(len(raw)) > maxBytes {
+		body.err = &requestError{http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", maxBytes)}
+		return body
+	}
+	body.raw = raw
+	if err := json.Unmarshal(raw, &body.req); err != nil {
+		body.err = &requestError{http.StatusBadRequest, fmt.Errorf("%w: %v", errBadRequest, err)}
+	}
+	return body
+}
 
-	return params, nil
+// bodyToCtx Read the body once, keeping the raw bytes in RequestKey and the
+// decoded request in GraphqlRequestKey
+func bodyToCtx(maxBytes int64) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		body := readRequestBody(r, maxBytes)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body.raw))
+		ctx = context.WithValue(ctx, requestBodyKey, body)
+		if body.err != nil {
+			return ctx
+		}
+		ctx = context.WithValue(ctx, RequestKey, body.raw)
+		return context.WithValue(ctx, GraphqlRequestKey, body.req)
+	}
+}
+
+func decodeGraphqlRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	body, ok := ctx.Value(requestBodyKey).(*requestBody)
+	if !ok {
+		body = readRequestBody(r, DefaultMaxBodyBytes)
+	}
+	if body.err != nil {
+		return nil, body.err
+	}
+	return body.req, nil
 }
 
 func encodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if res, ok := response.(*graphql.Response); ok {
+		return encodeGraphqlResponse(ctx, w, res)
+	}
 	responseJSON, err := json.Marshal(response)
 	if err != nil {
 		encodeError(ctx, err, w)
 		return nil
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_, err = w.Write(responseJSON)
-	return err
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writer, flush := compressedWriter(ctx, w, len(responseJSON))
+	if _, err := writer.Write(responseJSON); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// encodeGraphqlResponse Write res as json.Marshal would, copying the data
+// already marshaled by the service instead of marshaling it once more. It
+// isn't streamed: the data is still held whole, and scanned by json.Valid,
+// before the first byte is written.
+func encodeGraphqlResponse(ctx context.Context, w http.ResponseWriter, res *graphql.Response) error {
+	parts := [][]byte{[]byte("{")}
+	separator := []byte("")
+	if len(res.Errors) > 0 {
+		errorsJSON, err := json.Marshal(res.Errors)
+		if err != nil {
+			encodeError(ctx, err, w)
+			return nil
+		}
+		parts = append(parts, []byte(`"errors":`), errorsJSON)
+		separator = []byte(",")
+	}
+	if len(res.Data) > 0 {
+		if !json.Valid(res.Data) {
+			encodeError(ctx, errInvalidData, w)
+			return nil
+		}
+		parts = append(parts, separator, []byte(`"data":`), res.Data)
+		separator = []byte(",")
+	}
+	if len(res.Extensions) > 0 {
+		extensionsJSON, err := json.Marshal(res.Extensions)
+		if err != nil {
+			encodeError(ctx, err, w)
+			return nil
+		}
+		parts = append(parts, separator, []byte(`"extensions":`), extensionsJSON)
+	}
+	parts = append(parts, []byte("}"))
+	size := 0
+	for _, part := range parts {
+		size += len(part)
+	}
+
+	status := http.StatusOK
+	if graphqlResponseNegotiated(ctx) {
+		w.Header().Set("Content-Type", GraphqlResponseMediaType+"; charset=utf-8")
+		status = graphqlResponseStatus(res)
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	writer, flush := compressedWriter(ctx, w, size)
+	w.WriteHeader(status)
+	for _, part := range parts {
+		if _, err := writer.Write(part); err != nil {
+			return err
+		}
+	}
+	return flush()
 }
 
 type errorer interface {
 	error() error
 }
 
-func encodeError(_ context.Context, err error, w http.ResponseWriter) {
-	w.WriteHeader(http.StatusInternalServerError)
+// encodeError Answer err with its status code, 500 if it has none, as a
+// graphql response when GraphqlResponseMediaType was negotiated
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	code := http.StatusInternalServerError
+	var coder httptransport.StatusCoder
+	if errors.As(err, &coder) {
+		code = coder.StatusCode()
+	}
+	if graphqlResponseNegotiated(ctx) {
+		w.Header().Set("Content-Type", GraphqlResponseMediaType+"; charset=utf-8")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}})
+		return
+	}
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"error": err.Error(),
 	})
@@ -49,7 +211,12 @@ type authResponse struct {
 	Err   string `json:"err,omitempty"`
 }
 
-func authErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
+func authErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		encodeError(ctx, err, w)
+		return
+	}
 	code := http.StatusUnauthorized
 	msg := err.Error()
 
@@ -0,0 +1,116 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+	jwt "github.com/golang-jwt/jwt/v4"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+func serveBody(t *testing.T, body string, opts ...Option) (*httptest.ResponseRecorder, string) {
+	setup()
+	var buf bytes.Buffer
+	opts = append(opts,
+		WithSchemaSources(SchemaString("schema.graphql", schema)),
+		WithLogger(log.NewLogfmtLogger(&buf)),
+	)
+	h, err := NewHandlers("", &queryResolver, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	h.Handler().ServeHTTP(resp, req)
+	return resp, buf.String()
+}
+
+func TestHandlers_RequestBody_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		opts []Option
+		code int
+	}{
+		{"Invalid json", `{"query":`, nil, http.StatusBadRequest},
+		{"Too large", `{"query":"{ anyMethod(param: [1]) }"}`, []Option{WithMaxBodyBytes(10)}, http.StatusRequestEntityTooLarge},
+		{"Invalid json with authentication", `not json`, []Option{
+			WithJWT(string(Secret), jwt.SigningMethodHS512, func() jwt.Claims { return &customClaims{} }),
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, logged := serveBody(t, tt.body, tt.opts...)
+			if resp.Code != tt.code {
+				t.Errorf("Should have answered %v and returned %v %v\n", tt.code, resp.Code, resp.Body.String())
+			}
+			if !strings.Contains(resp.Body.String(), `"error"`) || !strings.Contains(logged, "err=") {
+				t.Errorf("Should have answered and logged the error and returned %v %v\n", resp.Body.String(), logged)
+			}
+		})
+	}
+}
+
+func TestHandlers_RequestBody_InContext(t *testing.T) {
+	//Arrange
+	body := `{"query":"{ anyMethod(param: [1]) }","variables":{"a":1}}`
+	var raw []byte
+	var parsed GraphqlRequest
+	inspect := httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+		raw, _ = ctx.Value(RequestKey).([]byte)
+		parsed, _ = ctx.Value(GraphqlRequestKey).(GraphqlRequest)
+		return ctx
+	})
+
+	//Act
+	resp, _ := serveBody(t, body, WithServerOptions(inspect))
+
+	//Assert
+	if resp.Code != http.StatusOK {
+		t.Errorf("Should have answered the request and returned %v\n", resp.Body.String())
+	}
+	if string(raw) != body || parsed.Query != "{ anyMethod(param: [1]) }" || parsed.Variables["a"] == nil {
+		t.Errorf("Should have kept the body and the request in the context and returned %s %+v\n", raw, parsed)
+	}
+}
+
+func TestEncodeResponse_ShouldWriteWhatJSONMarshalWould(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *graphql.Response
+	}{
+		{"Empty", &graphql.Response{}},
+		{"Data", &graphql.Response{Data: json.RawMessage(`{"a":[1,2]}`)}},
+		{"Errors", &graphql.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("failed")}}},
+		{"Extensions", &graphql.Response{Extensions: map[string]interface{}{"cost": 1}}},
+		{"All", &graphql.Response{
+			Data:       json.RawMessage(`{"a":null}`),
+			Errors:     []*gqlerrors.QueryError{gqlerrors.Errorf("failed")},
+			Extensions: map[string]interface{}{"cost": 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Arrange
+			want, _ := json.Marshal(tt.res)
+			resp := httptest.NewRecorder()
+
+			//Act
+			err := encodeResponse(context.Background(), resp, tt.res)
+
+			//Assert
+			if err != nil || resp.Body.String() != string(want) {
+				t.Errorf("Should have written %s and returned %s %v\n", want, resp.Body.String(), err)
+			}
+		})
+	}
+}
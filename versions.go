@@ -0,0 +1,179 @@
+package graphqlkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// VersionKey Context key of the API version selected for the request
+const VersionKey contextKey = "version"
+
+// VersionSelector Return the API version requested by r, or "" to use the
+// default version
+type VersionSelector func(r *http.Request) string
+
+// VersionFromHeader Select the version by the value of header, e.g. X-API-Version
+func VersionFromHeader(header string) VersionSelector {
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// VersionFromQuery Select the version by the value of the query parameter
+func VersionFromQuery(param string) VersionSelector {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(param)
+	}
+}
+
+// VersionFromPathPrefix Select the version by the path segment after prefix,
+// e.g. with prefix /graphql/ the path /graphql/v2 selects v2
+func VersionFromPathPrefix(prefix string) VersionSelector {
+	return func(r *http.Request) string {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			return ""
+		}
+		version := strings.TrimPrefix(r.URL.Path, prefix)
+		if i := strings.IndexByte(version, '/'); i >= 0 {
+			version = version[:i]
+		}
+		return version
+	}
+}
+
+// FirstVersion Use the first version selected by selectors
+func FirstVersion(selectors ...VersionSelector) VersionSelector {
+	return func(r *http.Request) string {
+		for _, selector := range selectors {
+			if version := selector(r); version != "" {
+				return version
+			}
+		}
+		return ""
+	}
+}
+
+// AddGraphqlVersion Create a new Service graphql for version of the API. When
+// there are versions, each request is routed by the version selector.
+func (h *Handlers) AddGraphqlVersion(version, schema string, resolver interface{}, opts ...graphql.SchemaOpt) error {
+	return h.AddGraphqlVersionFromSources(version, resolver, []SchemaSource{SchemaFile(schema)}, opts...)
+}
+
+// AddGraphqlVersionFromSources Create a new Service graphql for version of the
+// API, with the schema made of all sources
+func (h *Handlers) AddGraphqlVersionFromSources(
+	version string,
+	resolver interface{},
+	sources []SchemaSource,
+	opts ...graphql.SchemaOpt,
+) error {
+	opts, err := h.withSchemaOptions(opts)
+	if err != nil {
+		return err
+	}
+	service, _, err := LoadServiceFromSources(resolver, sources, opts...)
+	if err != nil {
+		return err
+	}
+	if h.versions == nil {
+		h.versions = make(map[string]Service)
+	}
+	if _, ok := h.versions[version]; !ok {
+		h.versionNames = append(h.versionNames, version)
+	}
+	h.versions[version] = service
+	return nil
+}
+
+// SetVersionSelector Choose how the version of each request is selected,
+// by default it is the X-API-Version header
+func (h *Handlers) SetVersionSelector(selector VersionSelector) {
+	h.versionSelector = selector
+}
+
+// SetDefaultVersion Name the version used when a request doesn't select one.
+// If there is a service added by AddGraphqlService it is known by this name,
+// otherwise the first version added is the default.
+func (h *Handlers) SetDefaultVersion(version string) {
+	h.defaultVersion = version
+}
+
+func (h *Handlers) versioned() bool {
+	return len(h.versions) > 0
+}
+
+// addVersions Replace the service by one routing to each version
+func (h *Handlers) addVersions() {
+	if !h.versioned() {
+		return
+	}
+	services, defaultVersion := h.routes()
+	h.service = &versionedService{services}
+	h.AddServerOptions(httptransport.ServerBefore(versionToCtx(h.selector(), defaultVersion)))
+}
+
+// routes Return the service of each version and the default version
+func (h *Handlers) routes() (map[string]Service, string) {
+	services := make(map[string]Service, len(h.versions)+1)
+	for version, service := range h.versions {
+		services[version] = service
+	}
+	defaultVersion := h.defaultVersion
+	if h.graphql != nil {
+		if _, ok := services[defaultVersion]; !ok {
+			services[defaultVersion] = h.graphql
+		}
+	} else if defaultVersion == "" {
+		defaultVersion = h.versionNames[0]
+	}
+	return services, defaultVersion
+}
+
+func (h *Handlers) selector() VersionSelector {
+	if h.versionSelector == nil {
+		return VersionFromHeader("X-API-Version")
+	}
+	return h.versionSelector
+}
+
+// serviceFor Return the graphql service of the version selected by r
+func (h *Handlers) serviceFor(r *http.Request) (Service, string) {
+	if !h.versioned() {
+		return h.graphql, ""
+	}
+	services, version := h.routes()
+	if selected := h.selector()(r); selected != "" {
+		version = selected
+	}
+	return services[version], version
+}
+
+type versionedService struct {
+	services map[string]Service
+}
+
+func (s *versionedService) Exec(ctx context.Context, req GraphqlRequest) *graphql.Response {
+	version, _ := ctx.Value(VersionKey).(string)
+	service, ok := s.services[version]
+	if !ok {
+		return &graphql.Response{Errors: []*gqlerrors.QueryError{
+			gqlerrors.Errorf("unknown API version %q", version),
+		}}
+	}
+	return service.Exec(ctx, req)
+}
+
+func versionToCtx(selector VersionSelector, defaultVersion string) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		version := selector(r)
+		if version == "" {
+			version = defaultVersion
+		}
+		return context.WithValue(ctx, VersionKey, version)
+	}
+}
@@ -0,0 +1,135 @@
+package graphqlkit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+var schemaV2 = `type Query {
+    anyMethod(param: [ID]!): [ID]
+    other: String
+}`
+
+func makeVersionedHandler(t *testing.T, opts ...Option) http.Handler {
+	fileV1, removeV1, err := CreateTempFile(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeV1()
+	fileV2, removeV2, err := CreateTempFile(schemaV2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeV2()
+	opts = append([]Option{
+		WithVersion("v1", fileV1.Name(), &queryResolver),
+		WithVersion("v2", fileV2.Name(), &extendedResolver{}),
+	}, opts...)
+	h, err := NewHandlers("", nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.Handler()
+}
+
+func TestVersions_WithVersionHeader_ShouldUseThatVersion(t *testing.T) {
+	//Arrange
+	setup()
+	var buf bytes.Buffer
+	handler := makeVersionedHandler(t, WithLogger(log.NewLogfmtLogger(&buf)))
+	req, _ := CreateGraphqlRequest("{ other }")
+	req.Header.Set("X-API-Version", "v2")
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	if resp.Body.String() != `{"data":{"other":null}}` {
+		t.Errorf("Should have answered with v2 and answered %s\n", resp.Body.String())
+	}
+	if !strings.Contains(buf.String(), "version=v2") {
+		t.Errorf("Should have logged the version, but it didn't.\n %v", buf.String())
+	}
+}
+
+func TestVersions_WithoutVersion_ShouldUseTheFirstVersion(t *testing.T) {
+	//Arrange
+	setup()
+	handler := makeVersionedHandler(t)
+	req, _ := CreateGraphqlRequest("{ other }")
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), `Cannot query field \"other\"`) {
+		t.Errorf("Should have answered with v1 and answered %s\n", resp.Body.String())
+	}
+}
+
+func TestVersions_WithPathPrefixAndUnknownVersion_ShouldReturnError(t *testing.T) {
+	//Arrange
+	setup()
+	handler := makeVersionedHandler(t, WithVersionSelector(VersionFromPathPrefix("/graphql/")))
+	req, _ := CreateGraphqlRequest("{ other }")
+	req.URL.Path = "/graphql/v3"
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), `unknown API version \"v3\"`) {
+		t.Errorf("Should have rejected the unknown version and answered %s\n", resp.Body.String())
+	}
+}
+
+func TestVersions_WithSchemaOptionsAfterTheVersions_ShouldReachThem(t *testing.T) {
+	//Arrange
+	setup()
+	handler := makeVersionedHandler(t, WithMaxDepth(1))
+	req, _ := CreateGraphqlRequest("{ __schema { types { name } } }")
+	req.Header.Set("X-API-Version", "v2")
+	resp := httptest.NewRecorder()
+
+	//Act
+	handler.ServeHTTP(resp, req)
+
+	//Assert
+	if !strings.Contains(resp.Body.String(), "exceeds max depth") {
+		t.Errorf("Should have rejected the query by its depth and returned %s\n", resp.Body.String())
+	}
+}
+
+func TestVersions_WithDecorators_ShouldPutTheSchemaOfTheVersionInTheContext(t *testing.T) {
+	//Arrange
+	setup()
+	var schemaString string
+	inspect := httptransport.ServerFinalizer(func(ctx context.Context, code int, r *http.Request) {
+		schemaString, _ = ctx.Value(SchemaKey).(string)
+	})
+	handler := makeVersionedHandler(t,
+		WithIntrospectionAuth(),
+		WithResponseCache(NewLRUCache(10), false),
+		WithServerOptions(inspect),
+	)
+	req, _ := CreateGraphqlRequest("{ other }")
+	req.Header.Set("X-API-Version", "v2")
+
+	//Act
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	//Assert
+	if !strings.Contains(schemaString, "other: String") {
+		t.Errorf("Should have put the schema of v2 in the context and returned %q\n", schemaString)
+	}
+}